@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var apiCmd = &cobra.Command{
+	Use:   "api <path>",
+	Short: "Make an authenticated API request",
+	Long: `Make an authenticated request to the platform API and print the response.
+
+The request uses the credentials, tenant and profile of a regular command.
+Paths without a leading slash are relative to /api/.
+
+The method defaults to GET, or POST when a body is given. Fields added with
+-f are sent as a JSON object, or as query parameters for GET requests.
+--input sends a file (or stdin with -) as the body instead; fields are then
+added to the query.
+
+With --paginate, the method defaults to GET and requests follow
+nextPageToken until the last page, printing every page.
+
+The command exits non-zero when the API returns an error status.
+
+Examples:
+  iot api devices
+  iot api devices/abc123 --include
+  iot api -X POST devices/abc123/approve -f reason="ok"
+  iot api devices --paginate -f pageSize=100
+  iot api -X PUT groups/line-1 --input group.json
+  echo '{"name":"line-2"}' | iot api groups --input -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAPI,
+}
+
+func init() {
+	rootCmd.AddCommand(apiCmd)
+
+	apiCmd.Flags().StringP("method", "X", "", "HTTP method (default GET, or POST with a body)")
+	apiCmd.Flags().StringArrayP("field", "f", nil, "Add a key=value field to the body or query")
+	apiCmd.Flags().String("input", "", "File to send as the request body (- for stdin)")
+	apiCmd.Flags().StringArrayP("header", "H", nil, "Add a \"Key: Value\" request header")
+	apiCmd.Flags().Bool("paginate", false, "Fetch all pages of a GET request")
+	apiCmd.Flags().BoolP("include", "i", false, "Print the response status and headers")
+}
+
+func runAPI(cmd *cobra.Command, args []string) error {
+	method, _ := cmd.Flags().GetString("method")
+	fieldArgs, _ := cmd.Flags().GetStringArray("field")
+	input, _ := cmd.Flags().GetString("input")
+	headerArgs, _ := cmd.Flags().GetStringArray("header")
+	paginate, _ := cmd.Flags().GetBool("paginate")
+	include, _ := cmd.Flags().GetBool("include")
+
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+	// Raw requests always reach the API
+	client = client.WithCache(api.CacheRevalidate)
+
+	path, query, err := apiPath(args[0], client.GetBaseURL())
+	if err != nil {
+		return err
+	}
+
+	fields, err := parseFields(fieldArgs)
+	if err != nil {
+		return err
+	}
+
+	var opts []api.RequestOption
+	for _, h := range headerArgs {
+		key, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid header %q: expected \"Key: Value\"", h)
+		}
+		opts = append(opts, api.WithHeader(strings.TrimSpace(key), strings.TrimSpace(value)))
+	}
+
+	if method == "" && paginate {
+		method = http.MethodGet
+	}
+
+	// Build the body; fields go into the query of methods without one
+	var body []byte
+	switch {
+	case input != "":
+		if body, err = readInput(input); err != nil {
+			return err
+		}
+	case len(fields) > 0 && (method == "" || methodHasBody(method)):
+		if body, err = json.Marshal(fields); err != nil {
+			return fmt.Errorf("failed to encode fields: %w", err)
+		}
+	}
+
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+	method = strings.ToUpper(method)
+
+	if body == nil {
+		for k, v := range fields {
+			query.Set(k, v)
+		}
+	}
+
+	if paginate && method != http.MethodGet {
+		return fmt.Errorf("--paginate is only supported for GET requests")
+	}
+
+	ctx := context.Background()
+	for {
+		target := path
+		if len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		resp, err := client.Do(ctx, method, target, reader, opts...)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if include {
+			printResponseHeaders(resp)
+		}
+		printResponseBody(data)
+
+		if err := api.ResponseError(resp, data); err != nil {
+			return err
+		}
+
+		token := nextPageToken(data)
+		if !paginate || token == "" || token == query.Get("pageToken") {
+			return nil
+		}
+		query.Set("pageToken", token)
+	}
+}
+
+// apiPath splits the path argument into an API path and its query. Relative
+// paths are resolved against /api/; full URLs must point at the configured API
+// so that credentials are never sent elsewhere.
+func apiPath(arg, baseURL string) (string, url.Values, error) {
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		rest, ok := strings.CutPrefix(arg, strings.TrimSuffix(baseURL, "/"))
+		if !ok || (rest != "" && rest[0] != '/') {
+			return "", nil, fmt.Errorf("URL %s is not on the configured API %s", arg, baseURL)
+		}
+		arg = rest
+	} else if !strings.HasPrefix(arg, "/") {
+		arg = "/api/" + arg
+	}
+
+	path, rawQuery, _ := strings.Cut(arg, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid query in %s: %w", arg, err)
+	}
+	return path, query, nil
+}
+
+// parseFields parses key=value arguments
+func parseFields(args []string) (map[string]string, error) {
+	fields := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid field %q: expected key=value", arg)
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+// readInput reads a request body from a file, or from stdin for "-"
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	return data, nil
+}
+
+// methodHasBody reports whether requests with the method usually carry a body
+func methodHasBody(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// printResponseHeaders prints the status line and headers of a response
+func printResponseHeaders(resp *http.Response) {
+	fmt.Printf("%s %s\n", resp.Proto, resp.Status)
+
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range resp.Header[k] {
+			fmt.Printf("%s: %s\n", k, v)
+		}
+	}
+	fmt.Println()
+}
+
+// printResponseBody prints a response body, indenting it if it is JSON
+func printResponseBody(data []byte) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	if json.Indent(&buf, trimmed, "", "  ") == nil {
+		buf.WriteByte('\n')
+		_, _ = os.Stdout.Write(buf.Bytes())
+		return
+	}
+
+	_, _ = os.Stdout.Write(data)
+	if data[len(data)-1] != '\n' {
+		fmt.Println()
+	}
+}
+
+// nextPageToken returns the nextPageToken of a paged JSON response
+func nextPageToken(data []byte) string {
+	var page struct {
+		NextPageToken string `json:"nextPageToken"`
+	}
+	if json.Unmarshal(data, &page) != nil {
+		return ""
+	}
+	return page.NextPageToken
+}
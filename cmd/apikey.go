@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "api-key",
+	Short: "Manage API keys for non-interactive access",
+	Long: `Manage API keys for CI pipelines and other service accounts.
+
+An API key is used instead of a login when IOT_API_KEY is set or the
+profile has an api_key entry.
+
+Examples:
+  iot auth api-key create ci-pipeline --scopes devices:read,files:write --expires 30d
+  iot auth api-key list
+  iot auth api-key revoke <key-id>
+  IOT_API_KEY=<key> iot device list`,
+}
+
+var apiKeyCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAPIKeyCreate,
+}
+
+var apiKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API keys",
+	Args:  cobra.NoArgs,
+	RunE:  runAPIKeyList,
+}
+
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <key-id>",
+	Short: "Revoke an API key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAPIKeyRevoke,
+}
+
+func init() {
+	authCmd.AddCommand(apiKeyCmd)
+	apiKeyCmd.AddCommand(apiKeyCreateCmd)
+	apiKeyCmd.AddCommand(apiKeyListCmd)
+	apiKeyCmd.AddCommand(apiKeyRevokeCmd)
+
+	// Create flags
+	apiKeyCreateCmd.Flags().StringSlice("scopes", nil, "Scopes granted to the key (e.g. devices:read,files:write)")
+	apiKeyCreateCmd.Flags().String("expires", "90d", "Lifetime of the key (e.g. 12h, 30d) or 'never'")
+	_ = apiKeyCreateCmd.MarkFlagRequired("scopes")
+}
+
+func runAPIKeyCreate(cmd *cobra.Command, args []string) error {
+	scopes, _ := cmd.Flags().GetStringSlice("scopes")
+	expiresStr, _ := cmd.Flags().GetString("expires")
+
+	req := api.CreateAPIKeyRequest{
+		Name:   args[0],
+		Scopes: scopes,
+	}
+
+	if expiresStr != "never" {
+		lifetime, err := parseLifetime(expiresStr)
+		if err != nil {
+			return err
+		}
+		expiresAt := time.Now().Add(lifetime).UTC()
+		req.ExpiresAt = &expiresAt
+	}
+
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	key, err := client.CreateAPIKey(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(key)
+	}
+
+	fmt.Printf("✓ Created API key %q (%s)\n", key.Name, key.ID)
+	fmt.Println()
+	fmt.Println("  " + key.Key)
+	fmt.Println()
+	fmt.Fprintln(os.Stderr, "Store this key now, it will not be shown again.")
+	fmt.Fprintln(os.Stderr, "Use it by setting IOT_API_KEY or api_key in a profile.")
+
+	return nil
+}
+
+func runAPIKeyList(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	keys, err := client.ListAPIKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(keys)
+	}
+
+	if len(keys) == 0 {
+		fmt.Println("No API keys found")
+		return nil
+	}
+
+	headers := []string{"ID", "NAME", "PREFIX", "SCOPES", "EXPIRES", "LAST USED"}
+	var rows [][]string
+
+	for _, k := range keys {
+		expires := "never"
+		if k.ExpiresAt != nil {
+			expires = k.ExpiresAt.Local().Format("2006-01-02")
+		}
+		lastUsed := "never"
+		if k.LastUsedAt != nil {
+			lastUsed = k.LastUsedAt.Local().Format("2006-01-02 15:04")
+		}
+		rows = append(rows, []string{
+			k.ID,
+			k.Name,
+			k.Prefix,
+			strings.Join(k.Scopes, ","),
+			expires,
+			lastUsed,
+		})
+	}
+
+	output.Table(headers, rows)
+	return nil
+}
+
+func runAPIKeyRevoke(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := client.RevokeAPIKey(ctx, args[0]); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	fmt.Printf("✓ Revoked API key %s\n", args[0])
+	return nil
+}
+
+// parseLifetime parses a duration that may also use a day suffix (e.g. 30d)
+func parseLifetime(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var devicePendingCmd = &cobra.Command{
+	Use:   "pending",
+	Short: "List devices waiting for approval",
+	Long: `List devices that have registered with the platform but have not been
+approved yet. Pending devices cannot be reached until they are approved.
+
+Examples:
+  iot device pending
+  iot device pending --json`,
+	Args: cobra.NoArgs,
+	RunE: runDevicePending,
+}
+
+var deviceApproveCmd = &cobra.Command{
+	Use:   "approve [device...]",
+	Short: "Approve devices waiting for approval",
+	Long: `Approve pending devices so they can connect to the platform.
+
+Devices are given by ID, name, unique name prefix or name@group, or selected
+among the pending devices by group (@group) or with --group, --name,
+--where and -l.
+
+Examples:
+  iot device approve gateway-01
+  iot device approve dev-0042 dev-0043 --yes
+  iot device approve --name gateway --json`,
+	ValidArgsFunction: completeDevices,
+	RunE:              runDeviceApprove,
+}
+
+var deviceRejectCmd = &cobra.Command{
+	Use:   "reject [device...]",
+	Short: "Reject devices waiting for approval",
+	Long: `Reject pending devices. Rejected devices cannot connect to the platform.
+
+Devices are given by ID, name, unique name prefix or name@group, or selected
+among the pending devices by group (@group) or with --group, --name,
+--where and -l.
+
+Examples:
+  iot device reject gateway-07 --reason "unknown serial number"
+  iot device reject --name test- --yes`,
+	ValidArgsFunction: completeDevices,
+	RunE:              runDeviceReject,
+}
+
+var deviceDecommissionCmd = &cobra.Command{
+	Use:   "decommission [device...]",
+	Short: "Permanently retire devices",
+	Long: `Decommission devices that have been taken out of service. A decommissioned
+device can no longer connect. This cannot be undone.
+
+Devices are given by ID, name, unique name prefix or name@group, or selected
+by group (@group) or with --group, --name, --where and -l.
+
+Examples:
+  iot device decommission press-01
+  iot device decommission -l site=bremen,hw=rev1
+  iot device decommission @old-line --yes
+  iot device decommission --where 'offline && lastSeen>30d'`,
+	ValidArgsFunction: completeDevices,
+	RunE:              runDeviceDecommission,
+}
+
+func init() {
+	deviceCmd.AddCommand(devicePendingCmd)
+	deviceCmd.AddCommand(deviceApproveCmd)
+	deviceCmd.AddCommand(deviceRejectCmd)
+	deviceCmd.AddCommand(deviceDecommissionCmd)
+
+	for _, c := range []*cobra.Command{deviceApproveCmd, deviceRejectCmd, deviceDecommissionCmd} {
+		addSelectorFlags(c)
+		c.Flags().Bool("yes", false, "Do not ask for confirmation")
+	}
+	deviceRejectCmd.Flags().String("reason", "", "Why the devices are rejected")
+}
+
+func runDevicePending(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	devices, err := client.ListPendingDevices(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list pending devices: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(devices)
+	}
+	if len(devices) == 0 {
+		fmt.Println("No devices waiting for approval")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(devices))
+	for _, d := range devices {
+		group := "-"
+		if d.GroupName != nil {
+			group = *d.GroupName
+		}
+		rows = append(rows, []string{d.Name, d.ID, group, d.LastSeenString()})
+	}
+	output.Table([]string{"NAME", "ID", "GROUP", "LAST SEEN"}, rows)
+	return nil
+}
+
+func runDeviceApprove(cmd *cobra.Command, args []string) error {
+	return runDeviceAction(cmd, args, "approve", "Approved", pendingDevices,
+		func(ctx context.Context, client *api.Client, id string) (*models.Device, error) {
+			return client.ApproveDevice(ctx, id)
+		})
+}
+
+func runDeviceReject(cmd *cobra.Command, args []string) error {
+	reason, _ := cmd.Flags().GetString("reason")
+	return runDeviceAction(cmd, args, "reject", "Rejected", pendingDevices,
+		func(ctx context.Context, client *api.Client, id string) (*models.Device, error) {
+			return client.RejectDevice(ctx, id, reason)
+		})
+}
+
+func runDeviceDecommission(cmd *cobra.Command, args []string) error {
+	return runDeviceAction(cmd, args, "decommission", "Decommissioned", allDevices,
+		func(ctx context.Context, client *api.Client, id string) (*models.Device, error) {
+			return client.DecommissionDevice(ctx, id)
+		})
+}
+
+// pendingDevices is the device source of approve and reject
+func pendingDevices(client *api.Client) deviceSource {
+	return client.ListPendingDevices
+}
+
+// allDevices is the device source of commands acting on any device
+func allDevices(client *api.Client) deviceSource {
+	return func(ctx context.Context) ([]models.Device, error) {
+		return listFleet(ctx, client)
+	}
+}
+
+// runDeviceAction selects devices, confirms the change and applies it to
+// each device. Failures are reported per device; the others still go ahead.
+func runDeviceAction(cmd *cobra.Command, args []string, verb, done string,
+	source func(*api.Client) deviceSource,
+	apply func(ctx context.Context, client *api.Client, id string) (*models.Device, error)) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	selected, err := selectDevices(ctx, cmd, client, args, source(client))
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		if IsJSON() {
+			return outputJSON([]models.Device{})
+		}
+		fmt.Println("No matching devices")
+		return nil
+	}
+
+	if err := confirmDevices(cmd, verb, selected); err != nil {
+		return err
+	}
+
+	changed := make([]models.Device, 0, len(selected))
+	var firstErr error
+	failed := 0
+	for _, ref := range selected {
+		device, err := apply(ctx, client, ref.ID)
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			fmt.Fprintf(os.Stderr, "✗ Failed to %s %s: %v\n", verb, ref.String(), err)
+			continue
+		}
+		changed = append(changed, *device)
+		if !IsJSON() && !IsQuiet() {
+			fmt.Printf("✓ %s %s (%s)\n", done, device.Name, device.ID)
+		}
+	}
+
+	if IsJSON() {
+		if err := outputJSON(changed); err != nil {
+			return err
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("failed to %s %d of %d devices: %w", verb, failed, len(selected), firstErr)
+	}
+	return nil
+}
@@ -1,15 +1,22 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/Bader-GmbH/iot-cli/internal/auth"
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/mdp/qrterminal/v3"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var authCmd = &cobra.Command{
@@ -24,14 +31,34 @@ var loginCmd = &cobra.Command{
 	Long: `Authenticate with the Bader IoT Platform.
 
 This will open your browser to complete authentication.
-Once you've logged in, the CLI will automatically receive your credentials.`,
+Once you've logged in, the CLI will automatically receive your credentials.
+
+With --loopback the CLI uses the standard OAuth2 authorization code flow
+with PKCE: it listens on 127.0.0.1 and the hosted login page redirects back
+to it, so the login completes as soon as you sign in.
+
+On machines without a browser, use --no-browser to print the login URL
+instead and open it on any other device. When stdout is not a terminal the
+URL is also shown as a QR code that can be scanned with a phone.
+
+Examples:
+  iot auth login                   # Open the browser
+  iot auth login --loopback        # OAuth2 + PKCE via the hosted login page
+  iot auth login --no-browser      # Print the URL (for SSH sessions and containers)
+  iot auth login --password        # Email, password and MFA code in the terminal
+  iot auth login --with-token < tokens.json`,
 	RunE: runLogin,
 }
 
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
 	Short: "Log out and clear stored credentials",
-	RunE:  runLogout,
+	Long: `Log out, revoking the refresh token on the server and clearing the
+stored credentials.
+
+With --all you are signed out of every session on every device, including
+browser logins and other machines running the CLI.`,
+	RunE: runLogout,
 }
 
 var statusCmd = &cobra.Command{
@@ -48,20 +75,52 @@ func init() {
 
 	// Login flags
 	loginCmd.Flags().Duration("timeout", 5*time.Minute, "Timeout for authentication")
+	loginCmd.Flags().Bool("no-browser", false, "Print the login URL instead of opening a browser")
+	loginCmd.Flags().Bool("qr", false, "Always show the login URL as a QR code")
+	loginCmd.Flags().Bool("with-token", false, "Read a token set as JSON from stdin")
+	loginCmd.Flags().Bool("password", false, "Log in with email and password instead of the browser")
+	loginCmd.Flags().String("email", "", "Email for --password login (prompted if not given)")
+	loginCmd.Flags().Bool("loopback", false, "Log in with OAuth2 + PKCE, receiving the result on a local redirect")
+	loginCmd.Flags().Int("loopback-port", 0, "Port for the --loopback redirect (default is a random free port)")
+	loginCmd.MarkFlagsMutuallyExclusive("with-token", "no-browser", "password", "loopback")
+	loginCmd.MarkFlagsMutuallyExclusive("qr", "with-token", "password", "loopback")
+
+	// Logout flags
+	logoutCmd.Flags().Bool("all", false, "Sign out of all sessions on all devices")
 }
 
 func runLogin(cmd *cobra.Command, args []string) error {
-	apiURL := viper.GetString("api_url")
-	if apiURL == "" {
-		apiURL = "https://api.iot.bader.solutions"
+	withToken, _ := cmd.Flags().GetBool("with-token")
+	if withToken {
+		return loginWithToken(os.Stdin)
+	}
+
+	profile, err := activeProfile()
+	if err != nil {
+		return err
 	}
 
 	timeout, _ := cmd.Flags().GetDuration("timeout")
+	noBrowser, _ := cmd.Flags().GetBool("no-browser")
+	showQR, _ := cmd.Flags().GetBool("qr")
+	usePassword, _ := cmd.Flags().GetBool("password")
+	useLoopback, _ := cmd.Flags().GetBool("loopback")
+
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	if usePassword {
+		email, _ := cmd.Flags().GetString("email")
+		return loginWithPassword(ctx, profile, email)
+	}
+
+	if useLoopback {
+		port, _ := cmd.Flags().GetInt("loopback-port")
+		return loginWithLoopback(ctx, profile, port)
+	}
+
 	// Create session auth client
-	sessionAuth := auth.NewSessionAuth(apiURL)
+	sessionAuth := auth.NewSessionAuth(profile)
 
 	fmt.Println("Creating authentication session...")
 
@@ -72,13 +131,32 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Println()
-	fmt.Println("Opening browser to complete authentication...")
-	fmt.Printf("If the browser doesn't open, visit:\n  %s\n", session.LoginURL)
-	fmt.Println()
-
-	// Open browser
-	if err := openBrowser(session.LoginURL); err != nil {
-		fmt.Printf("Warning: Could not open browser: %v\n", err)
+	if noBrowser {
+		session.PrintInstructions(os.Stdout)
+
+		// Without a terminal on stdout there is nothing to click; offer a code to scan instead
+		if showQR || !isTerminal() {
+			fmt.Fprintln(os.Stderr, "Or scan this QR code with your phone:")
+			qrterminal.GenerateHalfBlock(session.LoginURL, qrterminal.L, os.Stderr)
+			fmt.Fprintln(os.Stderr)
+		}
+	} else {
+		fmt.Println("Opening browser to complete authentication...")
+		fmt.Printf("If the browser doesn't open, visit:\n  %s\n", session.LoginURL)
+		if session.UserCode != "" {
+			fmt.Printf("Confirm that the page shows this code: %s\n", session.UserCode)
+		}
+		fmt.Println()
+		if showQR {
+			fmt.Fprintln(os.Stderr, "Or scan this QR code with your phone:")
+			qrterminal.GenerateHalfBlock(session.LoginURL, qrterminal.L, os.Stderr)
+			fmt.Fprintln(os.Stderr)
+		}
+
+		// Open browser
+		if err := openBrowser(session.LoginURL); err != nil {
+			fmt.Printf("Warning: Could not open browser: %v\n", err)
+		}
 	}
 
 	fmt.Println("Waiting for authentication...")
@@ -89,25 +167,181 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
-	// Store credentials
-	tokenStore, err := auth.NewTokenStore()
-	if err != nil {
-		return fmt.Errorf("failed to initialize token store: %w", err)
-	}
-
-	creds := &auth.Credentials{
+	return completeLogin(ctx, &auth.Credentials{
 		AccessToken:  status.AccessToken,
 		IDToken:      status.IDToken,
 		RefreshToken: status.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(status.ExpiresIn) * time.Second),
+	})
+}
+
+// loginWithLoopback runs the authorization code flow with PKCE against the
+// hosted login page, receiving the code on a loopback redirect
+func loginWithLoopback(ctx context.Context, profile *config.Profile, port int) error {
+	loopback := auth.NewLoopbackAuth(profile, port)
+
+	result, err := loopback.Login(ctx, func(authorizeURL string) error {
+		fmt.Println("Opening browser to complete authentication...")
+		fmt.Printf("If the browser doesn't open, visit:\n  %s\n", authorizeURL)
+		fmt.Println()
+
+		if err := openBrowser(authorizeURL); err != nil {
+			fmt.Printf("Warning: Could not open browser: %v\n", err)
+		}
+
+		fmt.Println("Waiting for authentication...")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return completeLogin(ctx, &auth.Credentials{
+		AccessToken:  result.AccessToken,
+		IDToken:      result.IDToken,
+		RefreshToken: result.RefreshToken,
+	})
+}
+
+// loginWithToken reads a token set as JSON from r and stores it. The JSON
+// has the same shape as a completed CLI session:
+//
+//	{"accessToken": "...", "idToken": "...", "refreshToken": "..."}
+//
+// The expiry is taken from the verified access token, so expiresIn is ignored.
+func loginWithToken(r io.Reader) error {
+	var tokens auth.CLISessionStatus
+	if err := json.NewDecoder(r).Decode(&tokens); err != nil {
+		return fmt.Errorf("failed to read token set from stdin: %w", err)
+	}
+
+	if tokens.AccessToken == "" || tokens.IDToken == "" {
+		return fmt.Errorf("token set must contain accessToken and idToken")
+	}
+
+	return completeLogin(context.Background(), &auth.Credentials{
+		AccessToken:  tokens.AccessToken,
+		IDToken:      tokens.IDToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// loginWithPassword authenticates against Cognito with email and password,
+// answering MFA and new-password challenges interactively
+func loginWithPassword(ctx context.Context, profile *config.Profile, email string) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("password login requires an interactive terminal")
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+
+	if email == "" {
+		fmt.Print("Email: ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read email: %w", err)
+		}
+		email = strings.TrimSpace(line)
+	}
+	if email == "" {
+		return fmt.Errorf("email cannot be empty")
+	}
+
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+
+	cognito, err := auth.NewCognitoClient(auth.CognitoConfigFromProfile(profile))
+	if err != nil {
+		return err
+	}
+
+	result, err := cognito.Authenticate(ctx, email, password, func(ctx context.Context, ch auth.Challenge) (string, error) {
+		return answerChallenge(reader, ch)
+	})
+	if err != nil {
+		return err
 	}
 
-	if err := tokenStore.Save(creds); err != nil {
+	return completeLogin(ctx, &auth.Credentials{
+		AccessToken:  result.AccessToken,
+		IDToken:      result.IDToken,
+		RefreshToken: result.RefreshToken,
+	})
+}
+
+// answerChallenge prompts the user for the answer to a Cognito challenge
+func answerChallenge(reader *bufio.Reader, ch auth.Challenge) (string, error) {
+	switch ch.Name {
+	case auth.ChallengeSoftwareTokenMFA:
+		return readLine(reader, "Authenticator code: ")
+	case auth.ChallengeSMSMFA:
+		if ch.Destination != "" {
+			return readLine(reader, fmt.Sprintf("Code sent to %s: ", ch.Destination))
+		}
+		return readLine(reader, "SMS code: ")
+	case auth.ChallengeNewPassword:
+		fmt.Println("A new password is required.")
+		password, err := readPassword("New password: ")
+		if err != nil {
+			return "", err
+		}
+		confirm, err := readPassword("Confirm new password: ")
+		if err != nil {
+			return "", err
+		}
+		if password != confirm {
+			return "", fmt.Errorf("passwords do not match")
+		}
+		return password, nil
+	default:
+		return "", fmt.Errorf("unsupported challenge: %s", ch.Name)
+	}
+}
+
+// readLine prints a prompt and reads a trimmed line
+func readLine(reader *bufio.Reader, prompt string) (string, error) {
+	fmt.Print(prompt)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// readPassword prints a prompt and reads a line from the terminal without echo
+func readPassword(prompt string) (string, error) {
+	fmt.Print(prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if len(password) == 0 {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	return string(password), nil
+}
+
+// completeLogin verifies and stores the credentials of a successful login and
+// reports who logged in
+func completeLogin(ctx context.Context, creds *auth.Credentials) error {
+	tokenStore, err := newTokenStore()
+	if err != nil {
+		return err
+	}
+
+	if err := tokenStore.Save(ctx, creds); err != nil {
 		return fmt.Errorf("failed to save credentials: %w", err)
 	}
+	_ = clearProfileCache()
 
-	// Reload to get parsed user info
-	creds, _ = tokenStore.Load()
+	// Reload to get the verified user info
+	creds, err = tokenStore.Load()
+	if err != nil {
+		return err
+	}
 
 	fmt.Println()
 	fmt.Println("✓ Successfully logged in!")
@@ -123,23 +357,64 @@ func runLogin(cmd *cobra.Command, args []string) error {
 }
 
 func runLogout(cmd *cobra.Command, args []string) error {
-	tokenStore, err := auth.NewTokenStore()
+	all, _ := cmd.Flags().GetBool("all")
+
+	tokenStore, err := newTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to initialize token store: %w", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Revoke server-side first; the local credentials are removed either way
+	var revokeErr error
+	if _, err := tokenStore.Load(); err == nil {
+		revokeErr = tokenStore.Revoke(ctx, all)
+	} else if all {
+		return err
 	}
 
 	if err := tokenStore.Delete(); err != nil {
 		return fmt.Errorf("failed to logout: %w", err)
 	}
+	_ = clearProfileCache()
+
+	if revokeErr != nil {
+		return fmt.Errorf("removed local credentials, but revoking the session failed: %w", revokeErr)
+	}
 
-	fmt.Println("✓ Logged out successfully")
+	if all {
+		fmt.Println("✓ Signed out of all sessions")
+	} else {
+		fmt.Println("✓ Logged out successfully")
+	}
 	return nil
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
-	tokenStore, err := auth.NewTokenStore()
+	profile, err := activeProfile()
+	if err != nil {
+		return err
+	}
+
+	if profile.APIKey != "" {
+		if IsJSON() {
+			return outputJSON(map[string]interface{}{
+				"profile":  profile.Name,
+				"loggedIn": true,
+				"method":   "api-key",
+			})
+		}
+		fmt.Println("✓ Authenticated with an API key")
+		fmt.Printf("  Profile:    %s\n", profile.Name)
+		fmt.Println()
+		return nil
+	}
+
+	tokenStore, err := newTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to initialize token store: %w", err)
+		return err
 	}
 
 	creds, err := tokenStore.Load()
@@ -150,11 +425,15 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Check expiration
-	expired := time.Now().After(creds.ExpiresAt)
+	// The access token is refreshed as any command would do
+	expired := !tokenStore.IsLoggedIn(context.Background())
+	if refreshed, err := tokenStore.Load(); err == nil {
+		creds = refreshed
+	}
 
 	if IsJSON() {
 		return outputJSON(map[string]interface{}{
+			"profile":   profile.Name,
 			"loggedIn":  !expired,
 			"email":     creds.Email,
 			"tenantId":  creds.TenantID,
@@ -170,6 +449,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Println("✓ Logged in")
+	fmt.Printf("  Profile:    %s\n", profile.Name)
 	if creds.Email != "" {
 		fmt.Printf("  Email:      %s\n", creds.Email)
 	}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/bootstrap"
+	"github.com/spf13/cobra"
+)
+
+var deviceBootstrapCmd = &cobra.Command{
+	Use:   "bootstrap --token <token-id>",
+	Short: "Generate a provisioning script that enrolls a machine",
+	Long: `Generate a script that installs the device agent on a Linux machine and
+registers it with a registration token. The device then waits for approval.
+
+Formats:
+  sh          Shell script to run as root on the machine
+  cloud-init  #cloud-config user data for the machine's first boot
+  systemd     Agent unit for images that already contain the agent binary
+
+The script contains the registration token, keep it private.
+
+Examples:
+  iot device bootstrap --token rt-8f2k > enroll.sh
+  ssh root@new-gateway sh < enroll.sh
+  iot device bootstrap --token rt-8f2k --format cloud-init -o user-data
+  iot device bootstrap --token rt-8f2k --format systemd -o b-agent.service`,
+	Args: cobra.NoArgs,
+	RunE: runDeviceBootstrap,
+}
+
+func init() {
+	deviceCmd.AddCommand(deviceBootstrapCmd)
+
+	deviceBootstrapCmd.Flags().String("token", "", "ID of the registration token to enroll with")
+	deviceBootstrapCmd.Flags().String("format", "sh", "Script format: "+strings.Join(bootstrap.Formats, ", "))
+	deviceBootstrapCmd.Flags().StringP("output", "o", "", "Write the script to a file instead of stdout")
+	_ = deviceBootstrapCmd.MarkFlagRequired("token")
+	_ = deviceBootstrapCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(bootstrap.Formats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runDeviceBootstrap(cmd *cobra.Command, args []string) error {
+	tokenID, _ := cmd.Flags().GetString("token")
+	format, _ := cmd.Flags().GetString("format")
+	outputPath, _ := cmd.Flags().GetString("output")
+	if !slices.Contains(bootstrap.Formats, format) {
+		return fmt.Errorf("invalid format %q: must be %s", format, strings.Join(bootstrap.Formats, ", "))
+	}
+
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	token, err := client.GetRegistrationToken(context.Background(), tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to get registration token: %w", err)
+	}
+	// A script with a dead token would only fail on the machine
+	if state := token.State(time.Now()); state != api.TokenActive {
+		return fmt.Errorf("registration token %s is %s, create a new one with 'iot registration-token create'", token.ID, state)
+	}
+	if token.Token == "" {
+		return fmt.Errorf("the API did not return the secret of registration token %s", token.ID)
+	}
+
+	cfg := bootstrap.Config{
+		APIURL:  client.GetBaseURL(),
+		TokenID: token.ID,
+		Token:   token.Token,
+	}
+	if token.GroupName != nil {
+		cfg.Group = *token.GroupName
+	}
+
+	var script bytes.Buffer
+	if err := bootstrap.Render(&script, format, cfg); err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		_, err := os.Stdout.Write(script.Bytes())
+		return err
+	}
+	// Only the owner may read the token; shell scripts can be run directly
+	mode := os.FileMode(0600)
+	if format == "sh" {
+		mode = 0700
+	}
+	// WriteFile keeps the mode of a file it overwrites, so an existing file
+	// is restricted before the token is written to it
+	if err := os.Chmod(outputPath, mode); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to restrict permissions of %s: %w", outputPath, err)
+	}
+	if err := os.WriteFile(outputPath, script.Bytes(), mode); err != nil {
+		return fmt.Errorf("failed to write script: %w", err)
+	}
+	if !IsQuiet() {
+		fmt.Fprintf(os.Stderr, "✓ Wrote %s script for registration token %s to %s\n", format, token.ID, outputPath)
+	}
+	return nil
+}
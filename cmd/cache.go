@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Bader-GmbH/iot-cli/internal/cache"
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of API responses",
+	Long: `The CLI caches API responses in the config directory. Fresh responses are
+reused for a few seconds (device lists, files) up to a few minutes (tenants,
+usage); older ones are revalidated with the API. Use --no-cache on any
+command to always ask the API.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached API responses",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClear,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	dir, err := config.GetCacheDir("")
+	if err != nil {
+		return fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	if err := cache.Open(dir).Clear(); err != nil {
+		return err
+	}
+
+	if !IsQuiet() {
+		fmt.Println("✓ Cache cleared")
+	}
+	return nil
+}
+
+// clearProfileCache removes the cached responses of the active profile, so
+// that a new login never sees data cached for the previous one
+func clearProfileCache() error {
+	profile, err := activeProfile()
+	if err != nil {
+		return err
+	}
+	dir, err := config.GetCacheDir(profile.Name)
+	if err != nil {
+		return err
+	}
+	return cache.Open(dir).Clear()
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the API calls made while completing a command line
+const completionTimeout = 3 * time.Second
+
+// listFleet returns all devices of the tenant. When the API cannot be
+// reached, the last cached device list is used instead.
+func listFleet(ctx context.Context, client *api.Client) ([]models.Device, error) {
+	return client.WithCache(api.CacheStaleIfError).CollectDevices(ctx, api.DeviceQuery{})
+}
+
+// deviceCandidates returns the names of the devices starting with prefix,
+// described by ID and connection status, each followed by suffix
+func deviceCandidates(prefix, suffix string) []string {
+	client, err := newAPIClient()
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	devices, err := listFleet(ctx, client)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, d := range devices {
+		if !strings.HasPrefix(d.Name, prefix) {
+			continue
+		}
+		status := "offline"
+		if d.Online {
+			status = "online"
+		}
+		candidates = append(candidates, d.Name+suffix+"\t"+d.ID+", "+status)
+	}
+	return candidates
+}
+
+// completeDevice completes the device argument of commands taking a single
+// device
+func completeDevice(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return deviceCandidates(toComplete, ""), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeDevices completes the device arguments of bulk commands
+func completeDevices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var candidates []string
+	for _, c := range deviceCandidates(toComplete, "") {
+		name, _, _ := strings.Cut(c, "\t")
+		if !slices.Contains(args, name) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates, cobra.ShellCompDirectiveNoFileComp
+}
+
+// groupCandidates returns the names of the groups starting with prefix,
+// described by their member counts
+func groupCandidates(prefix string) []string {
+	client, err := newAPIClient()
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	groups, err := client.WithCache(api.CacheStaleIfError).ListGroups(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, g := range groups {
+		if strings.HasPrefix(g.Name, prefix) {
+			candidates = append(candidates, fmt.Sprintf("%s\t%d devices, %d online", g.Name, g.DeviceCount, g.OnlineCount))
+		}
+	}
+	return candidates
+}
+
+// completeGroup completes the group argument of group commands
+func completeGroup(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return groupCandidates(toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGroupThenDevices completes a group followed by devices
+func completeGroupThenDevices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeGroup(cmd, args, toComplete)
+	}
+	return completeDevices(cmd, args[1:], toComplete)
+}
+
+// completeRemoteSource completes the device:path source of 'iot get'. The
+// local destination is completed as a file.
+func completeRemoteSource(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	if strings.Contains(toComplete, ":") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return deviceCandidates(toComplete, ":"), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRemoteDestination completes the arguments of 'iot put': local files
+// first, then the device:path destination. Device names are offered once a
+// local path is given, unless the word is clearly a local path.
+func completeRemoteDestination(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 || strings.ContainsAny(toComplete, ":/.~") {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	if candidates := deviceCandidates(toComplete, ":"); len(candidates) > 0 {
+		return candidates, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveDefault
+}
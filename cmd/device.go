@@ -3,15 +3,18 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/Bader-GmbH/iot-cli/internal/api"
 	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/Bader-GmbH/iot-cli/internal/query"
 	"github.com/Bader-GmbH/iot-cli/pkg/models"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var deviceCmd = &cobra.Command{
@@ -26,23 +29,54 @@ var deviceListCmd = &cobra.Command{
 	Short: "List all devices",
 	Long: `List all devices in your fleet.
 
+Devices are fetched page by page and printed as each page arrives. The
+--group and --name filters are applied by the server; --where selects
+devices with a query:
+
+  online && status=APPROVED && group in (line-1,line-2) && name~"^press-" && lastSeen>2h
+
+Queries test the fields online, offline, status, group, name, id and
+lastSeen with =, !=, ~ and !~ (regular expression), in (...), not in (...)
+and, for lastSeen, <, <=, > and >= with durations such as 30m, 2h or 7d.
+Conditions are combined with &&, || and !, and grouped with parentheses.
+
+Labels are tested as label.<key>, or with a label selector (-l):
+
+  site=hamburg,hw!=rev1,hw in (rev2,rev3),!spare
+
+Decommissioned devices are hidden unless --all is given or the query tests
+the status.
+
 Examples:
   iot device list              List all devices
-  iot device list --status online   Filter by status
+  iot device list --where 'offline && lastSeen>1d'   Filter with a query
+  iot device list -l site=hamburg,hw!=rev1   Filter by labels
+  iot device list -L site,hw        Show the site and hw labels as columns
+  iot device list --status pending  Filter by approval status
+  iot device list --name press      Filter by name
+  iot device list --sort-by lastSeen --limit 10   The devices seen longest ago
+  iot device list --limit 50        Show the first 50 devices
+  iot device list --page-token <token>   Continue a previous listing
+  iot device list --all-tenants     List devices of every tenant you belong to
+  iot device list --json-lines      Stream devices as JSON lines
   iot device list --json       Output as JSON`,
 	RunE: runDeviceList,
 }
 
 var deviceGetCmd = &cobra.Command{
-	Use:   "get <device-id>",
+	Use:   "get <device>",
 	Short: "Get device details",
 	Long: `Get detailed information about a specific device.
 
+The device is given by ID, name, unique name prefix or name@group.
+
 Examples:
   iot device get abc123
-  iot d get abc123 --json`,
-	Args: cobra.ExactArgs(1),
-	RunE: runDeviceGet,
+  iot device get press-01@line-1
+  iot d get press --json`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDevice,
+	RunE:              runDeviceGet,
 }
 
 func init() {
@@ -51,82 +85,275 @@ func init() {
 	deviceCmd.AddCommand(deviceGetCmd)
 
 	// List flags
-	deviceListCmd.Flags().String("status", "", "Filter by status (online, offline)")
+	deviceListCmd.Flags().String("status", "", "Filter by approval status (pending, approved, rejected, decommissioned)")
 	deviceListCmd.Flags().String("group", "", "Filter by group name")
+	deviceListCmd.Flags().String("name", "", "Filter by name (case-insensitive substring)")
+	deviceListCmd.Flags().Int("limit", 0, "Maximum number of devices to list (0 for all)")
+	deviceListCmd.Flags().Int("page-size", 0, "Number of devices fetched per request (0 for the server default)")
+	deviceListCmd.Flags().String("page-token", "", "Continue a listing from a page token")
+	deviceListCmd.Flags().Bool("json-lines", false, "Stream devices as JSON lines")
+	deviceListCmd.Flags().Bool("all-tenants", false, "List devices of every tenant you belong to")
+	deviceListCmd.Flags().String("sort-by", "", "Sort by name, id, status, online, group or lastSeen")
+	deviceListCmd.Flags().Bool("reverse", false, "Reverse the order (sorts by name without --sort-by)")
+	deviceListCmd.Flags().StringSliceP("label-columns", "L", nil, "Show the values of these labels as columns")
+	addQueryFlags(deviceListCmd)
 }
 
 func runDeviceList(cmd *cobra.Command, args []string) error {
-	apiURL := viper.GetString("api_url")
-	if apiURL == "" {
-		apiURL = "https://api.iot.bader.solutions"
-	}
-
-	client, err := api.NewClient(apiURL)
+	client, err := newAPIClient()
 	if err != nil {
 		return err
 	}
 
 	ctx := context.Background()
-	devices, err := client.ListDevices(ctx)
+	allTenants, _ := cmd.Flags().GetBool("all-tenants")
+	jsonLines, _ := cmd.Flags().GetBool("json-lines")
+
+	status, _ := cmd.Flags().GetString("status")
+	where, _ := cmd.Flags().GetString("where")
+	selector, _ := cmd.Flags().GetString("selector")
+	includeAll, _ := cmd.Flags().GetBool("all")
+	labelColumns, _ := cmd.Flags().GetStringSlice("label-columns")
+	sortBy, _ := cmd.Flags().GetString("sort-by")
+	reverse, _ := cmd.Flags().GetBool("reverse")
+
+	q := api.DeviceQuery{}
+	q.Group, _ = cmd.Flags().GetString("group")
+	q.Name, _ = cmd.Flags().GetString("name")
+	q.Limit, _ = cmd.Flags().GetInt("limit")
+	q.PageSize, _ = cmd.Flags().GetInt("page-size")
+	q.PageToken, _ = cmd.Flags().GetString("page-token")
+
+	switch status = strings.ToLower(status); status {
+	case "":
+	case "online", "offline":
+		// --status used to select the connection state
+		q.Status = status
+		if !IsQuiet() {
+			fmt.Fprintf(os.Stderr, "Warning: --status %s is deprecated, use --where %s\n", status, status)
+		}
+	case "pending", "approved", "rejected", "decommissioned":
+		where = joinQueries("status="+status, where)
+	default:
+		return fmt.Errorf("invalid status %q: must be pending, approved, rejected or decommissioned", status)
+	}
+	if q.Limit < 0 || q.PageSize < 0 {
+		return fmt.Errorf("--limit and --page-size must not be negative")
+	}
+	if allTenants && q.PageToken != "" {
+		return fmt.Errorf("--page-token cannot be combined with --all-tenants")
+	}
+	if reverse && sortBy == "" {
+		sortBy = "name"
+	}
+	if sortBy != "" {
+		if err := query.CheckSortKey(sortBy); err != nil {
+			return err
+		}
+		if q.PageToken != "" {
+			return fmt.Errorf("--page-token cannot be combined with --sort-by")
+		}
+	}
+
+	filter, expr, err := newDeviceFilter(where, selector, includeAll)
 	if err != nil {
-		return fmt.Errorf("failed to list devices: %w", err)
+		return err
+	}
+	q.Filter = filter
+	if expr != nil {
+		// Let the server narrow the listing where the query allows it
+		online, group := expr.ServerFilters()
+		if q.Status == "" {
+			q.Status = online
+		}
+		if q.Group == "" {
+			q.Group = group
+		}
 	}
 
-	// Apply filters
-	statusFilter, _ := cmd.Flags().GetString("status")
-	groupFilter, _ := cmd.Flags().GetString("group")
+	headers := []string{"NAME", "STATUS", "GROUP", "LAST SEEN"}
+	for _, key := range labelColumns {
+		headers = append(headers, strings.ToUpper(key))
+	}
+	var tenantNames map[string]string
+	if allTenants {
+		headers = append([]string{"TENANT"}, headers...)
+	}
 
-	if statusFilter != "" || groupFilter != "" {
-		devices = filterDevices(devices, statusFilter, groupFilter)
+	// Sorted and multi-tenant listings are complete before they are printed
+	var pages iter.Seq2[*api.DevicePage, error]
+	if allTenants || sortBy != "" {
+		limit := q.Limit
+		if sortBy != "" {
+			q.Limit = 0
+		}
+
+		var devices []models.Device
+		if allTenants {
+			devices, tenantNames, err = listDevicesAllTenants(ctx, client, q)
+		} else {
+			devices, err = client.CollectDevices(ctx, q)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list devices: %w", err)
+		}
+
+		if sortBy != "" {
+			_ = query.Sort(devices, sortBy, reverse)
+			if limit > 0 && len(devices) > limit {
+				devices = devices[:limit]
+			}
+		}
+		pages = func(yield func(*api.DevicePage, error) bool) {
+			yield(&api.DevicePage{Devices: devices}, nil)
+		}
+	} else {
+		pages = client.DevicePages(ctx, q)
 	}
 
-	// Output
-	if IsJSON() {
-		return outputJSON(devices)
+	// The JSON array is written once complete; the other formats stream
+	var all []models.Device
+	table := output.NewTableWriter(headers)
+	count := 0
+	nextToken := ""
+
+	for page, err := range pages {
+		if err != nil {
+			return fmt.Errorf("failed to list devices: %w", err)
+		}
+		count += len(page.Devices)
+		nextToken = page.NextPageToken
+
+		switch {
+		case jsonLines:
+			for _, d := range page.Devices {
+				if err := output.JSONLine(d); err != nil {
+					return err
+				}
+			}
+		case IsJSON():
+			all = append(all, page.Devices...)
+		case len(page.Devices) > 0:
+			table.Write(deviceRows(page.Devices, tenantNames, labelColumns))
+		}
 	}
 
-	if len(devices) == 0 {
+	if IsJSON() && !jsonLines {
+		if all == nil {
+			all = []models.Device{}
+		}
+		if err := outputJSON(all); err != nil {
+			return err
+		}
+	} else if count == 0 && !jsonLines {
 		fmt.Println("No devices found")
-		return nil
 	}
 
-	// Table output
-	headers := []string{"NAME", "STATUS", "GROUP", "LAST SEEN"}
-	var rows [][]string
+	// A token is only left over when the limit ended the listing early
+	if nextToken != "" && !IsQuiet() {
+		fmt.Fprintf(os.Stderr, "More devices available, continue with --page-token %s\n", nextToken)
+	}
+	return nil
+}
 
+// deviceRows formats devices as rows of the device list table. With tenant
+// names, each row starts with the device's tenant. The values of the given
+// labels are appended as extra columns.
+func deviceRows(devices []models.Device, tenantNames map[string]string, labelColumns []string) [][]string {
+	var rows [][]string
 	for _, d := range devices {
 		status := output.StatusIcon(d.Online) + " " + d.OnlineStatus()
 		group := ""
 		if d.GroupName != nil {
 			group = *d.GroupName
 		}
-		rows = append(rows, []string{
+		row := []string{
 			d.Name,
 			status,
 			group,
 			d.LastSeenString(),
-		})
+		}
+		for _, key := range labelColumns {
+			row = append(row, d.Labels[key])
+		}
+		if tenantNames != nil {
+			row = append([]string{tenantNames[d.TenantID]}, row...)
+		}
+		rows = append(rows, row)
 	}
-
-	output.Table(headers, rows)
-	return nil
+	return rows
 }
 
-func runDeviceGet(cmd *cobra.Command, args []string) error {
-	deviceID := args[0]
+// listDevicesAllTenants lists the devices of every tenant the user belongs to
+// concurrently, applying the query's filters and limit to the combined result.
+// It also returns the tenant names by ID for display.
+func listDevicesAllTenants(ctx context.Context, client *api.Client, query api.DeviceQuery) ([]models.Device, map[string]string, error) {
+	tenants, err := client.ListTenants(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	results := make([][]models.Device, len(tenants))
+	errs := make([]error, len(tenants))
+	names := make(map[string]string, len(tenants))
+
+	// Every tenant can contribute up to the limit before the result is cut
+	tenantQuery := query
+	tenantQuery.PageToken = ""
+
+	var wg sync.WaitGroup
+	for i, t := range tenants {
+		names[t.ID] = t.Name
+		if names[t.ID] == "" {
+			names[t.ID] = t.ID
+		}
+
+		wg.Add(1)
+		go func(i int, tenantID string) {
+			defer wg.Done()
+			devices, err := client.WithTenant(tenantID).CollectDevices(ctx, tenantQuery)
+			if err != nil {
+				errs[i] = fmt.Errorf("tenant %s: %w", tenantID, err)
+				return
+			}
+			// The API may leave out the tenant when it is implied by the request
+			for j := range devices {
+				if devices[j].TenantID == "" {
+					devices[j].TenantID = tenantID
+				}
+			}
+			results[i] = devices
+		}(i, t.ID)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
 
-	apiURL := viper.GetString("api_url")
-	if apiURL == "" {
-		apiURL = "https://api.iot.bader.solutions"
+	var devices []models.Device
+	for _, r := range results {
+		devices = append(devices, r...)
 	}
+	if query.Limit > 0 && len(devices) > query.Limit {
+		devices = devices[:query.Limit]
+	}
+	return devices, names, nil
+}
 
-	client, err := api.NewClient(apiURL)
+func runDeviceGet(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
 	if err != nil {
 		return err
 	}
 
 	ctx := context.Background()
-	device, err := client.GetDevice(ctx, deviceID)
+	ref, err := client.ResolveDevice(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	device, err := client.GetDevice(ctx, ref.ID)
 	if err != nil {
 		return fmt.Errorf("failed to get device: %w", err)
 	}
@@ -144,38 +371,14 @@ func runDeviceGet(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Group:       %s\n", *device.GroupName)
 	}
 	fmt.Printf("  Last Seen:   %s\n", device.LastSeenString())
+	if len(device.Labels) > 0 {
+		fmt.Printf("  Labels:      %s\n", device.LabelString())
+	}
 	fmt.Println()
 
 	return nil
 }
 
-func filterDevices(devices []models.Device, status, group string) []models.Device {
-	var filtered []models.Device
-
-	for _, d := range devices {
-		// Status filter
-		if status != "" {
-			if status == "online" && !d.Online {
-				continue
-			}
-			if status == "offline" && d.Online {
-				continue
-			}
-		}
-
-		// Group filter
-		if group != "" {
-			if d.GroupName == nil || !strings.EqualFold(*d.GroupName, group) {
-				continue
-			}
-		}
-
-		filtered = append(filtered, d)
-	}
-
-	return filtered
-}
-
 func outputJSON(data interface{}) error {
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/file"
+	"github.com/spf13/cobra"
+)
+
+// addTransferSelectorFlags adds the flags that pick the devices of a
+// transfer. -l is the bandwidth limit of transfers, so the label selector
+// has no shorthand here.
+func addTransferSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().String("selector", "", "Transfer with the devices matching a label selector, e.g. site=hamburg,hw!=rev1 (no -l, that is --limit)")
+	cmd.Flags().String("where", "", `Transfer with the devices matching a query, e.g. 'online && group=line-1'`)
+}
+
+// transferSelectsDevices reports whether a transfer's devices are selected
+// by flags rather than named in its remote path
+func transferSelectsDevices(cmd *cobra.Command) bool {
+	selector, _ := cmd.Flags().GetString("selector")
+	where, _ := cmd.Flags().GetString("where")
+	return selector != "" || where != ""
+}
+
+// parseTransferRemotePath parses the remote path of a transfer: device:path,
+// or :path when the devices are selected by flags
+func parseTransferRemotePath(cmd *cobra.Command, s string) (*file.RemotePath, error) {
+	if transferSelectsDevices(cmd) {
+		return file.ParseSelectedRemotePath(s)
+	}
+	if !file.IsRemotePath(s) {
+		return nil, fmt.Errorf("invalid remote path %q: expected format device:path", s)
+	}
+	return file.ParseRemotePath(s)
+}
+
+// selectTransferDevices returns the devices matching a transfer's selector
+// flags
+func selectTransferDevices(ctx context.Context, cmd *cobra.Command, client *api.Client) ([]api.DeviceRef, error) {
+	devices, err := selectDevices(ctx, cmd, client, nil, allDevices(client))
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no matching devices")
+	}
+	return devices, nil
+}
+
+// unsafeDirChars are replaced in the directory names of devices
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9_.@-]+`)
+
+// deviceDir returns the directory below dest that files downloaded from a
+// device go to: its name and ID, e.g. press-01@line-1-dev-1. Names come
+// from the API, so they are reduced to safe characters and the ID keeps
+// equally named devices apart.
+func deviceDir(dest string, d api.DeviceRef) (string, error) {
+	clean := func(s string) string {
+		return strings.TrimLeft(unsafeDirChars.ReplaceAllString(s, "_"), ".")
+	}
+	name := clean(d.String()) + "-" + clean(d.ID)
+	dir := filepath.Join(dest, name)
+	if rel, err := filepath.Rel(dest, dir); err != nil || rel != name {
+		return "", fmt.Errorf("invalid directory %q for device %s", name, d.ID)
+	}
+	return dir, nil
+}
+
+// transferEach runs a transfer with each device in turn, printing a line per
+// device and a total. A device that fails does not stop the others; the
+// returned error counts the failures.
+func transferEach(devices []api.DeviceRef, verb string, dryRun bool, transfer func(d api.DeviceRef) (*file.TransferResult, error)) error {
+	files, failed := 0, 0
+	var bytes int64
+	for _, d := range devices {
+		if !IsQuiet() && !dryRun {
+			fmt.Printf("%s %s...\n", verb, d.String())
+		}
+		result, err := transfer(d)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", d.String(), err)
+			continue
+		}
+		files += result.FilesTransferred
+		bytes += result.BytesTransferred
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  Warning: %s: %v\n", d.String(), e)
+		}
+	}
+
+	if !IsQuiet() {
+		if dryRun {
+			fmt.Printf("\nDry run complete. Would transfer %d file(s) with %d device(s).\n", files, len(devices)-failed)
+		} else {
+			fmt.Printf("\nTransferred %d file(s), %s total, with %d device(s)\n", files, file.FormatBytes(bytes), len(devices)-failed)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("transfer failed on %d of %d devices", failed, len(devices))
+	}
+	return nil
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/Bader-GmbH/iot-cli/internal/api"
 	"github.com/Bader-GmbH/iot-cli/internal/file"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var getCmd = &cobra.Command{
@@ -16,19 +15,28 @@ var getCmd = &cobra.Command{
 	Short: "Download files from a device",
 	Long: `Download files or directories from a device to your local machine.
 
-The remote path uses the format device:path where device is the device ID or name,
-and path is an absolute path on the device.
+The remote path uses the format device:path where device is the device ID,
+name, a unique name prefix or name@group, and path is an absolute path on
+the device.
 
 If no local path is specified, files are downloaded to the current directory.
 If the local path ends with /, it's treated as a directory.
 
+With --selector or --where, the files are downloaded from every matching
+device into a directory per device below the local path, named after the
+device and its ID (e.g. press-01@line-1-dev-1). The remote path is then
+written as :path. Unlike in device commands, the label selector has no -l
+shorthand here: -l is the bandwidth limit of transfers.
+
 Examples:
   iot get device-1:/var/log/app.log           # Download to ./app.log
   iot get device-1:/var/log/app.log ./logs/   # Download to ./logs/app.log
   iot get device-1:/etc/myapp/ -r             # Download directory recursively
-  iot get device-1:/var/log/app.log --limit 1M  # Limit to 1 MB/s`,
-	Args: cobra.RangeArgs(1, 2),
-	RunE: runGet,
+  iot get device-1:/var/log/app.log --limit 1M  # Limit to 1 MB/s
+  iot get :/var/log/app.log ./logs/ --selector hw=rev1  # To ./logs/<device>-<id>/app.log`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeRemoteSource,
+	RunE:              runGet,
 }
 
 func init() {
@@ -39,16 +47,12 @@ func init() {
 	getCmd.Flags().Bool("progress", true, "Show progress bar")
 	getCmd.Flags().BoolP("force", "f", false, "Overwrite existing files without prompt")
 	getCmd.Flags().Bool("dry-run", false, "Show what would be downloaded without actually downloading")
+	addTransferSelectorFlags(getCmd)
 }
 
 func runGet(cmd *cobra.Command, args []string) error {
 	// Parse source (device:path)
-	source := args[0]
-	if !file.IsRemotePath(source) {
-		return fmt.Errorf("invalid source %q: expected format device:path", source)
-	}
-
-	remote, err := file.ParseRemotePath(source)
+	remote, err := parseTransferRemotePath(cmd, args[0])
 	if err != nil {
 		return err
 	}
@@ -86,24 +90,42 @@ func runGet(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create API client
-	apiURL := viper.GetString("api_url")
-	if apiURL == "" {
-		apiURL = "https://api.iot.bader.solutions"
+	client, err := newAPIClient()
+	if err != nil {
+		return err
 	}
 
-	client, err := api.NewClient(apiURL)
+	ctx := context.Background()
+	if remote.Device == "" {
+		devices, err := selectTransferDevices(ctx, cmd, client)
+		if err != nil {
+			return err
+		}
+		if dest == "" {
+			dest = "."
+		}
+		return transferEach(devices, "Downloading from", dryRun, func(d api.DeviceRef) (*file.TransferResult, error) {
+			// A directory per device keeps equally named files apart
+			dir, err := deviceDir(dest, d)
+			if err != nil {
+				return nil, err
+			}
+			return file.Download(ctx, client, d.ID, remote.Path, dir+"/", opts)
+		})
+	}
+
+	device, err := client.ResolveDevice(ctx, remote.Device)
 	if err != nil {
 		return err
 	}
 
 	// Print header
 	if !IsQuiet() && !dryRun {
-		fmt.Printf("Downloading from %s...\n", remote.DeviceID)
+		fmt.Printf("Downloading from %s...\n", device.Name)
 	}
 
 	// Execute download
-	ctx := context.Background()
-	result, err := file.Download(ctx, client, remote.DeviceID, remote.Path, dest, opts)
+	result, err := file.Download(ctx, client, device.ID, remote.Path, dest, opts)
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var groupCmd = &cobra.Command{
+	Use:     "group",
+	Aliases: []string{"g"},
+	Short:   "Manage device groups",
+	Long: `Manage groups of devices, e.g. production lines or sites. A device belongs
+to at most one group.
+
+Groups are given by ID or name. Commands that select devices in bulk accept
+@group to select a group's devices.
+
+Examples:
+  iot group list
+  iot group create line-3 --description "Hall B"
+  iot group add-devices line-3 press-07 press-08
+  iot device approve @line-3`,
+}
+
+var groupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List groups with their member counts",
+	Args:  cobra.NoArgs,
+	RunE:  runGroupList,
+}
+
+var groupGetCmd = &cobra.Command{
+	Use:               "get <group>",
+	Short:             "Show a group and its devices",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeGroup,
+	RunE:              runGroupGet,
+}
+
+var groupCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a group",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGroupCreate,
+}
+
+var groupRenameCmd = &cobra.Command{
+	Use:               "rename <group> <new-name>",
+	Short:             "Rename a group",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeGroup,
+	RunE:              runGroupRename,
+}
+
+var groupDeleteCmd = &cobra.Command{
+	Use:   "delete <group>",
+	Short: "Delete a group",
+	Long: `Delete a group. Its devices are kept and no longer belong to a group.
+
+Examples:
+  iot group delete line-0
+  iot group delete line-0 --yes`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeGroup,
+	RunE:              runGroupDelete,
+}
+
+var groupAddDevicesCmd = &cobra.Command{
+	Use:   "add-devices <group> [device...]",
+	Short: "Move devices into a group",
+	Long: `Move devices into a group. Devices leave the group they were in before.
+
+Devices are given by ID, name, unique name prefix or name@group, or selected
+by group (@group) or with --group, --name, --where and -l.
+
+Examples:
+  iot group add-devices line-3 press-07 press-08
+  iot group add-devices line-3 @line-2
+  iot group add-devices line-3 --name lathe-
+  iot group add-devices line-3 -l site=hamburg`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeGroupThenDevices,
+	RunE:              runGroupAddDevices,
+}
+
+var groupRemoveDevicesCmd = &cobra.Command{
+	Use:   "remove-devices <group> [device...]",
+	Short: "Take devices out of a group",
+	Long: `Take devices out of a group. They are kept and no longer belong to a group.
+
+Examples:
+  iot group remove-devices line-3 press-07
+  iot group remove-devices line-3 --name test-
+  iot group remove-devices line-3 --where offline`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeGroupThenDevices,
+	RunE:              runGroupRemoveDevices,
+}
+
+func init() {
+	rootCmd.AddCommand(groupCmd)
+	groupCmd.AddCommand(groupListCmd)
+	groupCmd.AddCommand(groupGetCmd)
+	groupCmd.AddCommand(groupCreateCmd)
+	groupCmd.AddCommand(groupRenameCmd)
+	groupCmd.AddCommand(groupDeleteCmd)
+	groupCmd.AddCommand(groupAddDevicesCmd)
+	groupCmd.AddCommand(groupRemoveDevicesCmd)
+
+	groupCreateCmd.Flags().String("description", "", "Description of the group")
+	groupDeleteCmd.Flags().Bool("yes", false, "Do not ask for confirmation")
+	addSelectorFlags(groupAddDevicesCmd)
+	groupRemoveDevicesCmd.Flags().String("name", "", "Select members whose name contains this text (case-insensitive)")
+	addQueryFlags(groupRemoveDevicesCmd)
+}
+
+func runGroupList(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	groups, err := client.ListGroups(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(groups)
+	}
+	if len(groups) == 0 {
+		fmt.Println("No groups found")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(groups))
+	for _, g := range groups {
+		rows = append(rows, []string{
+			g.Name,
+			g.ID,
+			strconv.Itoa(g.DeviceCount),
+			strconv.Itoa(g.OnlineCount),
+			g.Description,
+		})
+	}
+	output.Table([]string{"NAME", "ID", "DEVICES", "ONLINE", "DESCRIPTION"}, rows)
+	return nil
+}
+
+func runGroupGet(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	group, err := client.ResolveGroup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	// The listing may be cached, fetch the current counts
+	if group, err = client.GetGroup(ctx, group.ID); err != nil {
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+
+	devices, err := client.CollectDevices(ctx, api.DeviceQuery{Group: group.Name})
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(struct {
+			*api.Group
+			Devices []models.Device `json:"devices"`
+		}{group, devices})
+	}
+
+	fmt.Printf("Group: %s\n", group.Name)
+	fmt.Printf("  ID:          %s\n", group.ID)
+	if group.Description != "" {
+		fmt.Printf("  Description: %s\n", group.Description)
+	}
+	fmt.Printf("  Devices:     %d (%d online)\n", group.DeviceCount, group.OnlineCount)
+	fmt.Println()
+
+	if len(devices) > 0 {
+		rows := make([][]string, 0, len(devices))
+		for _, d := range devices {
+			rows = append(rows, []string{d.Name, d.ID, output.StatusIcon(d.Online) + " " + d.OnlineStatus(), d.LastSeenString()})
+		}
+		output.Table([]string{"NAME", "ID", "STATUS", "LAST SEEN"}, rows)
+	}
+	return nil
+}
+
+func runGroupCreate(cmd *cobra.Command, args []string) error {
+	description, _ := cmd.Flags().GetString("description")
+
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	group, err := client.CreateGroup(context.Background(), api.CreateGroupRequest{Name: args[0], Description: description})
+	if err != nil {
+		return fmt.Errorf("failed to create group: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(group)
+	}
+	fmt.Printf("✓ Created group %s (%s)\n", group.Name, group.ID)
+	return nil
+}
+
+func runGroupRename(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	group, err := client.ResolveGroup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	renamed, err := client.RenameGroup(ctx, group.ID, args[1])
+	if err != nil {
+		return fmt.Errorf("failed to rename group: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(renamed)
+	}
+	fmt.Printf("✓ Renamed group %s to %s\n", group.Name, renamed.Name)
+	return nil
+}
+
+func runGroupDelete(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	group, err := client.ResolveGroup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	details := []string{fmt.Sprintf("%s (%s) with %d device(s)", group.Name, group.ID, group.DeviceCount)}
+	if err := confirm(cmd, "delete group "+group.Name, details); err != nil {
+		return err
+	}
+
+	if err := client.DeleteGroup(ctx, group.ID); err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+	fmt.Printf("✓ Deleted group %s\n", group.Name)
+	return nil
+}
+
+func runGroupAddDevices(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	group, err := client.ResolveGroup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectDevices(ctx, cmd, client, args[1:], allDevices(client))
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		fmt.Println("No matching devices")
+		return nil
+	}
+
+	ids := make([]string, 0, len(selected))
+	for _, d := range selected {
+		ids = append(ids, d.ID)
+	}
+	if err := client.AddDevicesToGroup(ctx, group.ID, ids); err != nil {
+		return fmt.Errorf("failed to add devices to group: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(selected)
+	}
+	if !IsQuiet() {
+		for _, d := range selected {
+			fmt.Printf("✓ Added %s (%s) to %s\n", d.Name, d.ID, group.Name)
+		}
+	}
+	return nil
+}
+
+func runGroupRemoveDevices(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	group, err := client.ResolveGroup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	members := func(ctx context.Context) ([]models.Device, error) {
+		return client.CollectDevices(ctx, api.DeviceQuery{Group: group.Name})
+	}
+	selected, err := selectDevices(ctx, cmd, client, args[1:], members)
+	if err != nil {
+		return err
+	}
+
+	removed := make([]api.DeviceRef, 0, len(selected))
+	var firstErr error
+	for _, d := range selected {
+		if err := client.RemoveDeviceFromGroup(ctx, group.ID, d.ID); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			fmt.Fprintf(os.Stderr, "✗ Failed to remove %s: %v\n", d.String(), err)
+			continue
+		}
+		removed = append(removed, d)
+		if !IsJSON() && !IsQuiet() {
+			fmt.Printf("✓ Removed %s (%s) from %s\n", d.Name, d.ID, group.Name)
+		}
+	}
+
+	if IsJSON() {
+		if err := outputJSON(removed); err != nil {
+			return err
+		}
+	} else if len(selected) == 0 {
+		fmt.Println("No matching devices")
+	}
+	if firstErr != nil {
+		return fmt.Errorf("failed to remove %d of %d devices: %w", len(selected)-len(removed), len(selected), firstErr)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var deviceLabelCmd = &cobra.Command{
+	Use:   "label <device> [key=value | key-]...",
+	Short: "Show or change a device's labels",
+	Long: `Show or change the labels of a device. Labels are key/value pairs that
+describe a device, e.g. its site or hardware revision, and select devices
+with -l in list and bulk commands.
+
+key=value sets a label, key- removes it; other labels are kept. Without
+changes, the device's labels are printed.
+
+Examples:
+  iot device label press-01 site=hamburg hw=rev3
+  iot device label press-01 site-
+  iot device label press-01
+  iot device list -l site=hamburg,hw!=rev1`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeDevice,
+	RunE:              runDeviceLabel,
+}
+
+func init() {
+	deviceCmd.AddCommand(deviceLabelCmd)
+}
+
+func runDeviceLabel(cmd *cobra.Command, args []string) error {
+	changes, err := api.ParseLabelChanges(args[1:])
+	if err != nil {
+		return err
+	}
+
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	ref, err := client.ResolveDevice(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		device, err := client.GetDevice(ctx, ref.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get device: %w", err)
+		}
+		if IsJSON() {
+			labels := device.Labels
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			return outputJSON(labels)
+		}
+		if len(device.Labels) == 0 {
+			fmt.Printf("%s has no labels\n", ref.String())
+			return nil
+		}
+		fmt.Println(strings.ReplaceAll(device.LabelString(), ",", "\n"))
+		return nil
+	}
+
+	device, err := client.UpdateDeviceLabels(ctx, ref.ID, changes)
+	if err != nil {
+		return fmt.Errorf("failed to update labels: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(device)
+	}
+	labels := device.LabelString()
+	if labels == "" {
+		labels = "none"
+	}
+	fmt.Printf("✓ Updated labels of %s: %s\n", ref.String(), labels)
+	return nil
+}
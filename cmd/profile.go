@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Bader-GmbH/iot-cli/internal/auth"
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage configuration profiles",
+	Long: `Manage named profiles for different environments and accounts.
+
+Each profile has its own API URL, auth URL, Cognito settings and credentials.
+The current profile is used unless --profile or IOT_PROFILE selects another one.
+
+Examples:
+  iot profile list
+  iot profile create staging --api-url https://api.staging.iot.bader.solutions
+  iot profile use staging
+  iot device list --profile production`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE:  runProfileList,
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch the current profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProfileUse,
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a profile",
+	Long: `Create a new profile. Settings that are not given use the built-in defaults.
+
+Examples:
+  iot profile create staging --api-url https://api.staging.iot.bader.solutions
+  iot profile create customer-a --cognito-user-pool-id eu-central-1_abc --cognito-client-id 123 --use`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProfileCreate,
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile and its stored credentials",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runProfileDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+
+	// Create flags
+	profileCreateCmd.Flags().String("api-url", "", "API URL")
+	profileCreateCmd.Flags().String("auth-url", "", "Auth URL")
+	profileCreateCmd.Flags().String("cognito-region", "", "Cognito region")
+	profileCreateCmd.Flags().String("cognito-user-pool-id", "", "Cognito user pool ID")
+	profileCreateCmd.Flags().String("cognito-client-id", "", "Cognito app client ID")
+	profileCreateCmd.Flags().String("cognito-endpoint", "", "Cognito endpoint override (e.g. a local fake)")
+	profileCreateCmd.Flags().String("cognito-issuer", "", "Token issuer override (defaults to the user pool URL)")
+	profileCreateCmd.Flags().String("credential-store", "", "Credential store (file, encrypted-file, helper)")
+	profileCreateCmd.Flags().String("credential-helper", "", "Credential helper for the helper store (e.g. libsecret, osxkeychain)")
+	profileCreateCmd.Flags().Bool("use", false, "Switch to the new profile")
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	f, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	current := viper.GetString("profile")
+	if current == "" {
+		current = f.Current()
+	}
+
+	var profiles []*config.Profile
+	for _, name := range f.ProfileNames() {
+		p, err := f.Profile(name)
+		if err != nil {
+			return err
+		}
+		profiles = append(profiles, p.WithDefaults())
+	}
+
+	if IsJSON() {
+		type profileJSON struct {
+			*config.Profile
+			Current bool `json:"current"`
+		}
+		var result []profileJSON
+		for _, p := range profiles {
+			result = append(result, profileJSON{Profile: p, Current: p.Name == current})
+		}
+		return outputJSON(result)
+	}
+
+	headers := []string{"CURRENT", "NAME", "API URL", "USER POOL"}
+	var rows [][]string
+
+	for _, p := range profiles {
+		marker := ""
+		if p.Name == current {
+			marker = "*"
+		}
+		rows = append(rows, []string{
+			marker,
+			p.Name,
+			p.APIURL,
+			p.Cognito.UserPoolID,
+		})
+	}
+
+	output.Table(headers, rows)
+	return nil
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	f, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Profile(name); err != nil {
+		return err
+	}
+
+	f.CurrentProfile = name
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Switched to profile %q\n", name)
+	return nil
+}
+
+func runProfileCreate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	f, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := f.Profiles[name]; exists {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	authURL, _ := cmd.Flags().GetString("auth-url")
+	region, _ := cmd.Flags().GetString("cognito-region")
+	userPoolID, _ := cmd.Flags().GetString("cognito-user-pool-id")
+	clientID, _ := cmd.Flags().GetString("cognito-client-id")
+	cognitoEndpoint, _ := cmd.Flags().GetString("cognito-endpoint")
+	cognitoIssuer, _ := cmd.Flags().GetString("cognito-issuer")
+	credentialStore, _ := cmd.Flags().GetString("credential-store")
+	credentialHelper, _ := cmd.Flags().GetString("credential-helper")
+	use, _ := cmd.Flags().GetBool("use")
+
+	f.Profiles[name] = &config.Profile{
+		Name:    name,
+		APIURL:  apiURL,
+		AuthURL: authURL,
+		Cognito: config.CognitoSettings{
+			Region:     region,
+			UserPoolID: userPoolID,
+			ClientID:   clientID,
+			Endpoint:   cognitoEndpoint,
+			Issuer:     cognitoIssuer,
+		},
+		CredentialStore:  credentialStore,
+		CredentialHelper: credentialHelper,
+	}
+
+	// Fail early on an unusable credential store
+	if _, err := auth.NewCredentialBackend(f.Profiles[name]); err != nil {
+		return err
+	}
+
+	if use {
+		f.CurrentProfile = name
+	}
+
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Created profile %q\n", name)
+	if !use {
+		fmt.Printf("  Run 'iot profile use %s' to switch to it.\n", name)
+	}
+	return nil
+}
+
+func runProfileDelete(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if name == config.DefaultProfileName {
+		return fmt.Errorf("the %q profile cannot be deleted", name)
+	}
+
+	f, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	profile, exists := f.Profiles[name]
+	if !exists {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	// Erase credentials first; external stores aren't covered by removing the profile directory
+	if tokenStore, err := auth.NewTokenStore(profile); err == nil {
+		if err := tokenStore.Delete(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not erase credentials: %v\n", err)
+		}
+	}
+
+	delete(f.Profiles, name)
+	if f.CurrentProfile == name {
+		f.CurrentProfile = ""
+	}
+
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	// Remove the profile's credentials and other state
+	profileDir, err := config.GetProfileDir(name)
+	if err == nil {
+		if err := os.RemoveAll(profileDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not remove %s: %v\n", profileDir, err)
+		}
+	}
+
+	fmt.Printf("✓ Deleted profile %q\n", name)
+	return nil
+}
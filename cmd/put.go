@@ -8,7 +8,6 @@ import (
 	"github.com/Bader-GmbH/iot-cli/internal/api"
 	"github.com/Bader-GmbH/iot-cli/internal/file"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var putCmd = &cobra.Command{
@@ -16,19 +15,27 @@ var putCmd = &cobra.Command{
 	Short: "Upload files to a device",
 	Long: `Upload files or directories from your local machine to a device.
 
-The remote path uses the format device:path where device is the device ID or name,
-and path is an absolute path on the device.
+The remote path uses the format device:path where device is the device ID,
+name, a unique name prefix or name@group, and path is an absolute path on
+the device.
 
 If the remote path ends with /, files are uploaded into that directory.
 Multiple local files can be specified, and they will all be uploaded to the destination.
 
+With --selector or --where, the files are uploaded to every matching device
+and the remote path is written as :path. A failure on one device does not
+stop the others. Unlike in device commands, the label selector has no -l
+shorthand here: -l is the bandwidth limit of transfers.
+
 Examples:
   iot put ./script.sh device-1:/opt/           # Upload to /opt/script.sh
   iot put ./config/ device-1:/etc/myapp/ -r    # Upload directory recursively
   iot put ./a.txt ./b.txt device-1:/tmp/       # Upload multiple files
-  iot put ./data.tar.gz device-1:/tmp/ --limit 500K  # Limit to 500 KB/s`,
-	Args: cobra.MinimumNArgs(2),
-	RunE: runPut,
+  iot put ./data.tar.gz device-1:/tmp/ --limit 500K  # Limit to 500 KB/s
+  iot put ./app.yaml :/etc/app/ --selector site=hamburg  # Upload to every device in Hamburg`,
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: completeRemoteDestination,
+	RunE:              runPut,
 }
 
 func init() {
@@ -39,6 +46,8 @@ func init() {
 	putCmd.Flags().Bool("progress", true, "Show progress bar")
 	putCmd.Flags().BoolP("force", "f", false, "Overwrite existing files without prompt")
 	putCmd.Flags().Bool("dry-run", false, "Show what would be uploaded without actually uploading")
+	putCmd.Flags().Bool("yes", false, "Do not ask for confirmation when uploading to selected devices")
+	addTransferSelectorFlags(putCmd)
 }
 
 func runPut(cmd *cobra.Command, args []string) error {
@@ -46,11 +55,7 @@ func runPut(cmd *cobra.Command, args []string) error {
 	dest := args[len(args)-1]
 	localPaths := args[:len(args)-1]
 
-	if !file.IsRemotePath(dest) {
-		return fmt.Errorf("invalid destination %q: expected format device:path", dest)
-	}
-
-	remote, err := file.ParseRemotePath(dest)
+	remote, err := parseTransferRemotePath(cmd, dest)
 	if err != nil {
 		return err
 	}
@@ -94,24 +99,39 @@ func runPut(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create API client
-	apiURL := viper.GetString("api_url")
-	if apiURL == "" {
-		apiURL = "https://api.iot.bader.solutions"
+	client, err := newAPIClient()
+	if err != nil {
+		return err
 	}
 
-	client, err := api.NewClient(apiURL)
+	ctx := context.Background()
+	if remote.Device == "" {
+		devices, err := selectTransferDevices(ctx, cmd, client)
+		if err != nil {
+			return err
+		}
+		if !dryRun {
+			if err := confirmDevices(cmd, "upload to", devices); err != nil {
+				return err
+			}
+		}
+		return transferEach(devices, "Uploading to", dryRun, func(d api.DeviceRef) (*file.TransferResult, error) {
+			return file.Upload(ctx, client, localPaths, d.ID, remote.Path, opts)
+		})
+	}
+
+	device, err := client.ResolveDevice(ctx, remote.Device)
 	if err != nil {
 		return err
 	}
 
 	// Print header
 	if !IsQuiet() && !dryRun {
-		fmt.Printf("Uploading to %s...\n", remote.DeviceID)
+		fmt.Printf("Uploading to %s...\n", device.Name)
 	}
 
 	// Execute upload
-	ctx := context.Background()
-	result, err := file.Upload(ctx, client, localPaths, remote.DeviceID, remote.Path, opts)
+	result, err := file.Upload(ctx, client, localPaths, device.ID, remote.Path, opts)
 	if err != nil {
 		return fmt.Errorf("upload failed: %w", err)
 	}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var registrationTokenCmd = &cobra.Command{
+	Use:     "registration-token",
+	Aliases: []string{"rt"},
+	Short:   "Manage the tokens new devices enroll with",
+	Long: `Manage registration tokens. A new device registers itself with a token
+and then waits for approval, in the token's group if it has one.
+
+Tokens expire, can be limited to a number of devices and can be revoked.
+'iot device bootstrap' generates a provisioning script that enrolls a
+machine with a token.
+
+Examples:
+  iot registration-token create --group line-3 --expires 7d --max-uses 50
+  iot registration-token list
+  iot registration-token revoke <token-id>
+  iot device bootstrap --token <token-id> > enroll.sh`,
+}
+
+var registrationTokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a registration token",
+	Args:  cobra.NoArgs,
+	RunE:  runRegistrationTokenCreate,
+}
+
+var registrationTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registration tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runRegistrationTokenList,
+}
+
+var registrationTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token-id>",
+	Short: "Revoke a registration token",
+	Long: `Revoke a registration token so no more devices can register with it.
+Devices that already registered with it are not affected.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRegistrationTokenRevoke,
+}
+
+func init() {
+	rootCmd.AddCommand(registrationTokenCmd)
+	registrationTokenCmd.AddCommand(registrationTokenCreateCmd)
+	registrationTokenCmd.AddCommand(registrationTokenListCmd)
+	registrationTokenCmd.AddCommand(registrationTokenRevokeCmd)
+
+	registrationTokenCreateCmd.Flags().String("group", "", "Group that devices registering with the token join")
+	registrationTokenCreateCmd.Flags().String("expires", "7d", "Lifetime of the token (e.g. 12h, 30d) or 'never'")
+	registrationTokenCreateCmd.Flags().Int("max-uses", 0, "Number of devices that can register with the token (0 for unlimited)")
+	_ = registrationTokenCreateCmd.RegisterFlagCompletionFunc("group", completeGroup)
+}
+
+func runRegistrationTokenCreate(cmd *cobra.Command, args []string) error {
+	groupRef, _ := cmd.Flags().GetString("group")
+	expiresStr, _ := cmd.Flags().GetString("expires")
+	maxUses, _ := cmd.Flags().GetInt("max-uses")
+
+	if maxUses < 0 {
+		return fmt.Errorf("--max-uses must not be negative")
+	}
+	req := api.CreateRegistrationTokenRequest{MaxUses: maxUses}
+	if expiresStr != "never" {
+		lifetime, err := parseLifetime(expiresStr)
+		if err != nil {
+			return err
+		}
+		expiresAt := time.Now().Add(lifetime).UTC()
+		req.ExpiresAt = &expiresAt
+	}
+
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if groupRef != "" {
+		group, err := client.ResolveGroup(ctx, groupRef)
+		if err != nil {
+			return err
+		}
+		req.GroupID = &group.ID
+	}
+
+	token, err := client.CreateRegistrationToken(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to create registration token: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(token)
+	}
+
+	fmt.Printf("✓ Created registration token %s\n", token.ID)
+	fmt.Println()
+	fmt.Println("  " + token.Token)
+	fmt.Println()
+	fmt.Fprintf(os.Stderr, "Generate a provisioning script with 'iot device bootstrap --token %s'.\n", token.ID)
+	return nil
+}
+
+func runRegistrationTokenList(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	tokens, err := client.ListRegistrationTokens(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list registration tokens: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(tokens)
+	}
+
+	if len(tokens) == 0 {
+		fmt.Println("No registration tokens found")
+		return nil
+	}
+
+	now := time.Now()
+	headers := []string{"ID", "GROUP", "USES", "EXPIRES", "STATE", "CREATED"}
+	var rows [][]string
+	for _, t := range tokens {
+		group := ""
+		if t.GroupName != nil {
+			group = *t.GroupName
+		}
+		uses := strconv.Itoa(t.Uses)
+		if t.MaxUses > 0 {
+			uses += "/" + strconv.Itoa(t.MaxUses)
+		}
+		expires := "never"
+		if t.ExpiresAt != nil {
+			expires = t.ExpiresAt.Local().Format("2006-01-02 15:04")
+		}
+		rows = append(rows, []string{
+			t.ID,
+			group,
+			uses,
+			expires,
+			t.State(now),
+			t.CreatedAt.Local().Format("2006-01-02"),
+		})
+	}
+
+	output.Table(headers, rows)
+	return nil
+}
+
+func runRegistrationTokenRevoke(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.RevokeRegistrationToken(context.Background(), args[0]); err != nil {
+		return fmt.Errorf("failed to revoke registration token: %w", err)
+	}
+
+	fmt.Printf("✓ Revoked registration token %s\n", args[0])
+	return nil
+}
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/auth"
 	"github.com/Bader-GmbH/iot-cli/internal/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -11,10 +15,14 @@ import (
 
 var (
 	cfgFile        string
+	profileName    string
+	tenantFlag     string
 	jsonOutputFlag bool
 	yamlOutputFlag bool
 	quiet          bool
 	verbose        bool
+	traceFile      string
+	noCache        bool
 )
 
 var rootCmd = &cobra.Command{
@@ -32,28 +40,73 @@ Get started:
 	SilenceUsage: true,
 }
 
-func Execute() error {
+// Exit codes of the CLI. They are part of the CLI's interface: scripts may
+// branch on them, so existing values must not change.
+const (
+	ExitOK            = 0
+	ExitError         = 1 // Any failure without a more specific code
+	ExitUnauthorized  = 3 // Not logged in, or the login or API key was rejected
+	ExitForbidden     = 4 // Authenticated, but not allowed to do this
+	ExitNotFound      = 5 // The device, file or other resource does not exist
+	ExitConflict      = 6 // The resource already exists or was changed concurrently
+	ExitDeviceOffline = 7 // The device is not connected
+	ExitTimeout       = 8 // A wait such as device watch --until did not finish in time
+)
+
+// Execute runs the root command and returns the process exit code
+func Execute() int {
 	// Check for updates in background after command completes
 	defer CheckForUpdateInBackground()
 
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	writeTraceFile()
+	return ExitCode(err)
+}
+
+// ExitCode maps an error to the documented exit code of its failure class
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, api.ErrUnauthorized):
+		return ExitUnauthorized
+	case errors.Is(err, api.ErrForbidden):
+		return ExitForbidden
+	case errors.Is(err, api.ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, api.ErrConflict):
+		return ExitConflict
+	case errors.Is(err, api.ErrDeviceOffline):
+		return ExitDeviceOffline
+	case errors.Is(err, errTimedOut):
+		return ExitTimeout
+	default:
+		return ExitError
+	}
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, initTransport)
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/iot/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile to use (default is the current profile)")
+	rootCmd.PersistentFlags().StringVar(&tenantFlag, "tenant", "", "Tenant to act on (default is the profile's or login's tenant)")
 	rootCmd.PersistentFlags().BoolVarP(&jsonOutputFlag, "json", "j", false, "Output in JSON format")
 	rootCmd.PersistentFlags().BoolVarP(&yamlOutputFlag, "yaml", "y", false, "Output in YAML format")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Suppress non-essential output")
-	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Verbose output for debugging")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Verbose output for debugging, including HTTP requests")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace-file", "", "Write HTTP requests to a HAR file (e.g. out.har)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Revalidate cached API responses instead of using them while fresh")
 
 	// Bind flags to viper (errors only occur if flag doesn't exist, which is a programmer error)
+	_ = viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	_ = viper.BindPFlag("tenant", rootCmd.PersistentFlags().Lookup("tenant"))
 	_ = viper.BindPFlag("output.json", rootCmd.PersistentFlags().Lookup("json"))
 	_ = viper.BindPFlag("output.yaml", rootCmd.PersistentFlags().Lookup("yaml"))
 	_ = viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
 	_ = viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	_ = viper.BindPFlag("no_cache", rootCmd.PersistentFlags().Lookup("no-cache"))
 }
 
 func initConfig() {
@@ -73,6 +126,7 @@ func initConfig() {
 
 	// Environment variables
 	viper.SetEnvPrefix("IOT")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	// Read config file if it exists
@@ -99,3 +153,92 @@ func IsQuiet() bool {
 func IsVerbose() bool {
 	return viper.GetBool("verbose")
 }
+
+// configFilePath returns the config file that profile changes are written to
+func configFilePath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	return config.GetConfigPath()
+}
+
+// loadConfigFile reads the config file holding the profiles
+func loadConfigFile() (*config.File, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine config path: %w", err)
+	}
+	return config.LoadFile(path)
+}
+
+// activeProfile resolves the profile selected by --profile, IOT_PROFILE or
+// the current profile in the config file. --tenant or IOT_TENANT override
+// the profile's tenant.
+func activeProfile() (*config.Profile, error) {
+	f, err := loadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	name := viper.GetString("profile")
+	if name == "" {
+		name = f.Current()
+	}
+
+	p, err := f.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// A top-level api_url (or IOT_API_URL) applies to profiles that don't set their own
+	resolved := *p
+	if resolved.APIURL == "" {
+		resolved.APIURL = viper.GetString("api_url")
+	}
+	// The global retry section applies to profiles that don't set their own
+	if resolved.Retry.MaxRetries == nil && viper.IsSet("retry.max_retries") {
+		maxRetries := viper.GetInt("retry.max_retries")
+		resolved.Retry.MaxRetries = &maxRetries
+	}
+	if resolved.Retry.MaxDelay == 0 && viper.IsSet("retry.max_delay") {
+		resolved.Retry.MaxDelay = viper.GetDuration("retry.max_delay")
+	}
+	if tenant := viper.GetString("tenant"); tenant != "" {
+		resolved.Tenant = tenant
+	}
+	if key := os.Getenv(config.APIKeyEnv); key != "" {
+		resolved.APIKey = key
+	}
+
+	return resolved.WithDefaults(), nil
+}
+
+// newAPIClient creates an API client for the active profile
+func newAPIClient() (*api.Client, error) {
+	profile, err := activeProfile()
+	if err != nil {
+		return nil, err
+	}
+	client, err := api.NewClient(profile)
+	if err != nil {
+		return nil, err
+	}
+	if viper.GetBool("no_cache") {
+		client = client.WithCache(api.CacheRevalidate)
+	}
+	return client, nil
+}
+
+// newTokenStore creates a token store for the active profile
+func newTokenStore() (*auth.TokenStore, error) {
+	profile, err := activeProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	tokenStore, err := auth.NewTokenStore(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize token store: %w", err)
+	}
+	return tokenStore, nil
+}
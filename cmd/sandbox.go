@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/sandbox"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var sandboxCmd = &cobra.Command{
+	Use:   "sandbox",
+	Short: "Run a local fake platform for demos and testing",
+	Long: `Run a fake IoT platform on localhost for demos, training and testing
+scripts without a real tenant.`,
+}
+
+var sandboxStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the sandbox",
+	Long: `Start a fake IoT platform on localhost and keep it running until Ctrl+C.
+
+The sandbox serves the device, file, terminal, usage and login APIs and
+simulates a fleet of devices. Each device's file system is a local directory,
+and 'iot device ssh' opens a local shell in it. Any email and password are
+accepted for login.
+
+Examples:
+  iot sandbox start
+  iot sandbox start --devices 20 --offline 3 --groups line-1,line-2
+  iot sandbox start --save-profile sandbox
+  iot sandbox start --addr 127.0.0.1:0 --api-key test-key`,
+	Args: cobra.NoArgs,
+	RunE: runSandboxStart,
+}
+
+func init() {
+	rootCmd.AddCommand(sandboxCmd)
+	sandboxCmd.AddCommand(sandboxStartCmd)
+
+	sandboxStartCmd.Flags().String("addr", "127.0.0.1:8765", "Address to listen on")
+	sandboxStartCmd.Flags().Int("devices", 5, "Number of simulated devices")
+	sandboxStartCmd.Flags().Int("offline", 1, "How many of the devices are offline")
+	sandboxStartCmd.Flags().Int("pending", 2, "Additional devices waiting for approval")
+	sandboxStartCmd.Flags().StringSlice("groups", []string{"line-1", "line-2"}, "Groups the devices are spread over")
+	sandboxStartCmd.Flags().String("shell", "", "Shell for terminal sessions (default /bin/sh)")
+	sandboxStartCmd.Flags().String("data-dir", "", "Keep device files in this directory (default is a temporary directory)")
+	sandboxStartCmd.Flags().String("api-key", "", "API key accepted by the sandbox (default is random)")
+	sandboxStartCmd.Flags().Bool("auto-login", true, "Complete browser logins without opening the login page")
+	sandboxStartCmd.Flags().String("save-profile", "", "Save a profile for the sandbox under this name")
+}
+
+func runSandboxStart(cmd *cobra.Command, args []string) error {
+	opts := sandbox.Options{}
+	opts.Addr, _ = cmd.Flags().GetString("addr")
+	opts.Devices, _ = cmd.Flags().GetInt("devices")
+	opts.Offline, _ = cmd.Flags().GetInt("offline")
+	opts.Pending, _ = cmd.Flags().GetInt("pending")
+	opts.Groups, _ = cmd.Flags().GetStringSlice("groups")
+	opts.Shell, _ = cmd.Flags().GetString("shell")
+	opts.DataDir, _ = cmd.Flags().GetString("data-dir")
+	opts.APIKey, _ = cmd.Flags().GetString("api-key")
+	opts.AutoLogin, _ = cmd.Flags().GetBool("auto-login")
+	saveProfile, _ := cmd.Flags().GetString("save-profile")
+
+	if opts.Offline > opts.Devices {
+		opts.Offline = opts.Devices
+	}
+
+	name := saveProfile
+	if name == "" {
+		name = "sandbox"
+	}
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	server, err := sandbox.Start(opts)
+	if err != nil {
+		return fmt.Errorf("failed to start sandbox: %w", err)
+	}
+	profile := server.Profile(name)
+
+	if saveProfile != "" {
+		f, err := loadConfigFile()
+		if err == nil {
+			f.Profiles[name] = profile
+			err = f.Save()
+		}
+		if err != nil {
+			_ = server.Close(context.Background())
+			return err
+		}
+	}
+
+	if IsJSON() {
+		if err := outputJSON(map[string]interface{}{
+			"url":     server.URL(),
+			"apiKey":  server.APIKey(),
+			"dataDir": server.DataDir(),
+			"profile": profile,
+		}); err != nil {
+			_ = server.Close(context.Background())
+			return err
+		}
+	} else {
+		printSandboxInfo(server, profile, saveProfile != "")
+	}
+
+	// Serve until interrupted
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	if !IsQuiet() {
+		fmt.Fprintln(os.Stderr, "\nStopping sandbox...")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Close(ctx)
+}
+
+// printSandboxInfo shows how to point the CLI at a running sandbox
+func printSandboxInfo(server *sandbox.Server, profile *config.Profile, saved bool) {
+	fmt.Printf("✓ Sandbox running at %s\n", server.URL())
+	fmt.Printf("  Device files: %s\n", server.DataDir())
+	fmt.Println()
+
+	if saved {
+		fmt.Printf("Saved profile %q. Log in with:\n", profile.Name)
+	} else {
+		// Show the profile in the layout of the config file
+		data, _ := yaml.Marshal(map[string]interface{}{
+			"profiles": map[string]*config.Profile{profile.Name: profile},
+		})
+		fmt.Println("Add this profile to your config file (or start with --save-profile):")
+		fmt.Println()
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			fmt.Println("  " + line)
+		}
+		fmt.Println()
+		fmt.Println("Then log in with:")
+	}
+	fmt.Printf("  iot auth login --profile %s --no-browser\n", profile.Name)
+	fmt.Println()
+	fmt.Println("Or use the API key in scripts:")
+	fmt.Printf("  IOT_API_KEY=%s iot device list --profile %s\n", server.APIKey(), profile.Name)
+	fmt.Println()
+	fmt.Println("Press Ctrl+C to stop.")
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/query"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// errAborted is returned when the user declines a confirmation prompt
+var errAborted = errors.New("aborted")
+
+// deviceSource lists the devices that selector flags pick from
+type deviceSource func(ctx context.Context) ([]models.Device, error)
+
+// addSelectorFlags adds the flags that pick devices in bulk
+func addSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().String("group", "", "Select the devices of a group")
+	cmd.Flags().String("name", "", "Select devices whose name contains this text (case-insensitive)")
+	addQueryFlags(cmd)
+}
+
+// addQueryFlags adds the flags that filter devices with a query or a label
+// selector
+func addQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("where", "", `Select devices matching a query, e.g. 'online && lastSeen>2h'`)
+	cmd.Flags().StringP("selector", "l", "", "Select devices by label, e.g. site=hamburg,hw!=rev1")
+	cmd.Flags().Bool("all", false, "Include decommissioned devices")
+}
+
+// newDeviceFilter parses a --where query and a --selector label selector
+// into a device filter. Decommissioned devices are left out unless all is
+// set or the query tests the status. The returned query is nil without one.
+func newDeviceFilter(where, selector string, all bool) (func(d *models.Device) bool, *query.Expr, error) {
+	var expr, labels *query.Expr
+	var err error
+	if where != "" {
+		if expr, err = query.Parse(where); err != nil {
+			return nil, nil, err
+		}
+		all = all || expr.Tests("status")
+	}
+	if selector != "" {
+		if labels, err = query.ParseSelector(selector); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	return func(d *models.Device) bool {
+		if !all && d.Status == models.DeviceStatusDecommissioned {
+			return false
+		}
+		return (expr == nil || expr.Match(d, now)) && (labels == nil || labels.Match(d, now))
+	}, expr, nil
+}
+
+// selectDevices returns the devices a bulk command acts on: the devices
+// given as arguments and the devices of source matching the selector flags.
+// An argument may also name a group, written as @group or as a plain group
+// name that is not a device, to select the group's devices from source.
+// Devices picked from source are subject to --all. Each device is returned
+// once.
+func selectDevices(ctx context.Context, cmd *cobra.Command, client *api.Client, args []string, source deviceSource) ([]api.DeviceRef, error) {
+	group, _ := cmd.Flags().GetString("group")
+	name, _ := cmd.Flags().GetString("name")
+	where, _ := cmd.Flags().GetString("where")
+	selector, _ := cmd.Flags().GetString("selector")
+	all, _ := cmd.Flags().GetBool("all")
+	if len(args) == 0 && group == "" && name == "" && where == "" && selector == "" {
+		return nil, fmt.Errorf("specify devices or select them with --group, --name, --where or --selector")
+	}
+
+	filter, _, err := newDeviceFilter(where, selector, all)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []api.DeviceRef
+	seen := make(map[string]bool)
+	add := func(ref *api.DeviceRef) {
+		if !seen[ref.ID] {
+			seen[ref.ID] = true
+			selected = append(selected, *ref)
+		}
+	}
+
+	// The source is listed at most once
+	var sourced []models.Device
+	listSource := func() ([]models.Device, error) {
+		if sourced != nil {
+			return sourced, nil
+		}
+		devices, err := source(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list devices: %w", err)
+		}
+		sourced = devices
+		return sourced, nil
+	}
+
+	for _, arg := range args {
+		groupRef, isGroup := strings.CutPrefix(arg, "@")
+		var deviceErr error
+		if !isGroup {
+			ref, err := client.ResolveDevice(ctx, arg)
+			if err == nil {
+				add(ref)
+				continue
+			}
+			if !errors.Is(err, api.ErrNotFound) {
+				return nil, err
+			}
+			deviceErr = err
+		}
+
+		g, err := client.ResolveGroup(ctx, groupRef)
+		if err != nil {
+			if deviceErr != nil {
+				// Neither a device nor a group, report the device lookup
+				return nil, deviceErr
+			}
+			return nil, err
+		}
+		devices, err := listSource()
+		if err != nil {
+			return nil, err
+		}
+		for i := range devices {
+			// Group arguments are only subject to --all
+			if inGroup(&devices[i], g.ID, g.Name) && (all || devices[i].Status != models.DeviceStatusDecommissioned) {
+				add(api.RefOf(&devices[i]))
+			}
+		}
+	}
+
+	if group != "" || name != "" || where != "" || selector != "" {
+		devices, err := listSource()
+		if err != nil {
+			return nil, err
+		}
+		for i := range devices {
+			d := &devices[i]
+			if group != "" && !inGroup(d, group, group) {
+				continue
+			}
+			if name != "" && !strings.Contains(strings.ToLower(d.Name), strings.ToLower(name)) {
+				continue
+			}
+			if !filter(d) {
+				continue
+			}
+			add(api.RefOf(d))
+		}
+	}
+	return selected, nil
+}
+
+// inGroup reports whether a device belongs to the group with the given ID
+// or name
+func inGroup(d *models.Device, id, name string) bool {
+	return (d.GroupID != nil && *d.GroupID == id) || (d.GroupName != nil && strings.EqualFold(*d.GroupName, name))
+}
+
+// confirmDevices lists the devices a command is about to change and asks
+// whether to go ahead
+func confirmDevices(cmd *cobra.Command, action string, devices []api.DeviceRef) error {
+	lines := make([]string, 0, len(devices))
+	for _, d := range devices {
+		lines = append(lines, fmt.Sprintf("%s (%s)", d.String(), d.ID))
+	}
+	return confirm(cmd, fmt.Sprintf("%s %d device(s)", action, len(devices)), lines)
+}
+
+// confirm shows what a command is about to do and asks whether to go ahead,
+// unless --yes was given. Without a terminal to ask on, --yes is required.
+func confirm(cmd *cobra.Command, action string, details []string) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("refusing to %s without confirmation, use --yes", action)
+	}
+
+	fmt.Fprintf(os.Stderr, "About to %s:\n", action)
+	for _, line := range details {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+	fmt.Fprintf(os.Stderr, "Continue? [y/N] ")
+
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errAborted
+	}
+}
+
+// joinQueries combines queries with &&, skipping empty ones
+func joinQueries(queries ...string) string {
+	var parts []string
+	for _, q := range queries {
+		if q != "" {
+			parts = append(parts, q)
+		}
+	}
+	if len(parts) <= 1 {
+		return strings.Join(parts, "")
+	}
+	return "(" + strings.Join(parts, ") && (") + ")"
+}
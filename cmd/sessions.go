@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List your active CLI sessions",
+	Long: `List the CLI sessions of your account, with the machine, IP address
+and age of each, so that stale sessions can be revoked.
+
+Examples:
+  iot auth sessions
+  iot auth sessions revoke <session-id>
+  iot auth logout --all        # Revoke every session at once`,
+	Args: cobra.NoArgs,
+	RunE: runSessionsList,
+}
+
+var sessionsRevokeCmd = &cobra.Command{
+	Use:   "revoke <session-id>",
+	Short: "Revoke a CLI session",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSessionsRevoke,
+}
+
+func init() {
+	authCmd.AddCommand(sessionsCmd)
+	sessionsCmd.AddCommand(sessionsRevokeCmd)
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	sessions, err := client.ListSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(sessions)
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found")
+		return nil
+	}
+
+	headers := []string{"CURRENT", "ID", "DEVICE", "PLATFORM", "IP", "AGE", "LAST USED"}
+	var rows [][]string
+
+	for _, s := range sessions {
+		marker := ""
+		if s.Current {
+			marker = "*"
+		}
+		lastUsed := "never"
+		if s.LastUsedAt != nil {
+			lastUsed = formatAge(*s.LastUsedAt) + " ago"
+		}
+		rows = append(rows, []string{
+			marker,
+			s.ID,
+			s.DeviceName,
+			s.Platform,
+			s.IPAddress,
+			formatAge(s.CreatedAt),
+			lastUsed,
+		})
+	}
+
+	output.Table(headers, rows)
+	return nil
+}
+
+func runSessionsRevoke(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := client.RevokeSession(ctx, args[0]); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	fmt.Printf("✓ Revoked session %s\n", args[0])
+	return nil
+}
+
+// formatAge formats the time since t in its largest whole unit (e.g. 3d, 5h, 12m)
+func formatAge(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
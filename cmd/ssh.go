@@ -5,10 +5,8 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/Bader-GmbH/iot-cli/internal/api"
 	"github.com/Bader-GmbH/iot-cli/internal/terminal"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var sshCmd = &cobra.Command{
@@ -16,15 +14,18 @@ var sshCmd = &cobra.Command{
 	Short: "Open a terminal session to a device",
 	Long: `Open an interactive terminal session to a remote device.
 
-The device can be specified by name or ID. The device must be online
+The device can be specified by ID, name, a unique name prefix or
+name@group when names repeat across groups. The device must be online
 and approved for the connection to succeed.
 
 Examples:
   iot ssh my-device          # Connect by device name
   iot ssh abc123             # Connect by device ID
+  iot ssh press-01@line-2    # Connect by name within a group
   iot ssh ec2-instance       # Connect to EC2 instance`,
-	Args: cobra.ExactArgs(1),
-	RunE: runSSH,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDevice,
+	RunE:              runSSH,
 }
 
 func init() {
@@ -32,40 +33,37 @@ func init() {
 }
 
 func runSSH(cmd *cobra.Command, args []string) error {
-	deviceID := args[0]
-
 	// Create API client
-	apiURL := viper.GetString("api_url")
-	if apiURL == "" {
-		apiURL = "https://api.iot.bader.solutions"
-	}
-
-	client, err := api.NewClient(apiURL)
+	client, err := newAPIClient()
 	if err != nil {
 		return err
 	}
 
 	ctx := context.Background()
 
-	// Check if device is online
+	device, err := client.ResolveDevice(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
 	if !IsQuiet() {
-		fmt.Fprintf(os.Stderr, "Connecting to %s...\n", deviceID)
+		fmt.Fprintf(os.Stderr, "Connecting to %s...\n", device.Name)
 	}
 
 	// Create terminal session
-	session, err := client.CreateTerminalSession(ctx, deviceID)
+	session, err := client.CreateTerminalSession(ctx, device.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
 
 	// Get credentials for WebSocket auth
-	accessToken, tenantID, err := client.GetCredentials()
+	headers, err := client.AuthHeaders(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get credentials: %w", err)
 	}
 
 	// Connect to WebSocket
-	termSession, err := terminal.Connect(ctx, client.GetBaseURL(), session.SessionID, accessToken, tenantID)
+	termSession, err := terminal.Connect(ctx, client.GetBaseURL(), session.SessionID, headers)
 	if err != nil {
 		// Clean up the session on error
 		_ = client.CloseTerminalSession(ctx, session.SessionID)
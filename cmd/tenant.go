@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var tenantCmd = &cobra.Command{
+	Use:   "tenant",
+	Short: "Manage tenant membership",
+	Long: `List and switch between the tenants you are a member of.
+
+Requests are sent for the tenant selected with 'iot tenant use', or for the
+tenant of your login if none is selected. Use --tenant or IOT_TENANT to act
+on another tenant for a single command.
+
+Examples:
+  iot tenant list
+  iot tenant use customer-a
+  iot device list --tenant customer-b
+  iot device list --all-tenants`,
+}
+
+var tenantListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tenants you belong to",
+	Args:  cobra.NoArgs,
+	RunE:  runTenantList,
+}
+
+var tenantUseCmd = &cobra.Command{
+	Use:   "use <tenant>",
+	Short: "Switch the tenant of the current profile",
+	Long: `Switch the tenant of the current profile. The tenant can be given by ID or name.
+Selecting the tenant of your login clears the selection.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTenantUse,
+}
+
+func init() {
+	rootCmd.AddCommand(tenantCmd)
+	tenantCmd.AddCommand(tenantListCmd)
+	tenantCmd.AddCommand(tenantUseCmd)
+}
+
+func runTenantList(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tenants, err := client.ListTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	current, err := client.TenantID()
+	if err != nil {
+		return err
+	}
+
+	if IsJSON() {
+		type tenantJSON struct {
+			api.Tenant
+			Current bool `json:"current"`
+		}
+		result := []tenantJSON{}
+		for _, t := range tenants {
+			result = append(result, tenantJSON{Tenant: t, Current: t.ID == current})
+		}
+		return outputJSON(result)
+	}
+
+	if len(tenants) == 0 {
+		fmt.Println("No tenants found")
+		return nil
+	}
+
+	headers := []string{"CURRENT", "ID", "NAME", "ROLE"}
+	var rows [][]string
+
+	for _, t := range tenants {
+		marker := ""
+		if t.ID == current {
+			marker = "*"
+		}
+		rows = append(rows, []string{marker, t.ID, t.Name, t.Role})
+	}
+
+	output.Table(headers, rows)
+	return nil
+}
+
+func runTenantUse(cmd *cobra.Command, args []string) error {
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tenants, err := client.ListTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	tenant, err := findTenant(tenants, args[0])
+	if err != nil {
+		return err
+	}
+
+	profile, err := activeProfile()
+	if err != nil {
+		return err
+	}
+
+	f, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	p, err := f.Profile(profile.Name)
+	if err != nil {
+		return err
+	}
+
+	// The login's own tenant needs no override
+	p.Tenant = tenant.ID
+	if !client.UsesAPIKey() {
+		tokenStore, err := newTokenStore()
+		if err != nil {
+			return err
+		}
+		if home, err := tokenStore.GetTenantID(); err == nil && home == tenant.ID {
+			p.Tenant = ""
+		}
+	}
+
+	f.Profiles[profile.Name] = p
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Switched to tenant %s (%s)\n", tenant.Name, tenant.ID)
+	return nil
+}
+
+// findTenant looks up a tenant by ID or name
+func findTenant(tenants []api.Tenant, idOrName string) (*api.Tenant, error) {
+	var byName []api.Tenant
+	for _, t := range tenants {
+		if t.ID == idOrName {
+			return &t, nil
+		}
+		if t.Name == idOrName {
+			byName = append(byName, t)
+		}
+	}
+
+	switch len(byName) {
+	case 0:
+		return nil, fmt.Errorf("you are not a member of tenant %q (run 'iot tenant list')", idOrName)
+	case 1:
+		return &byName[0], nil
+	default:
+		return nil, fmt.Errorf("tenant name %q is ambiguous, use the tenant ID", idOrName)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
+	"github.com/spf13/viper"
+)
+
+// harRecorder collects requests for --trace-file; nil when not tracing to a file
+var harRecorder *transport.HARRecorder
+
+// initTransport sets up the HTTP transport shared by all clients. An invalid
+// transport configuration fails every request rather than every command.
+func initTransport() {
+	var rt http.RoundTripper
+	base, err := transport.New(transportOptions())
+	if err != nil {
+		rt = transport.Failing(fmt.Errorf("invalid transport configuration: %w", err))
+	} else {
+		rt = base
+	}
+
+	if traceFile != "" {
+		harRecorder = transport.NewHARRecorder(Version)
+	}
+	if IsVerbose() || harRecorder != nil {
+		var log io.Writer
+		if IsVerbose() {
+			log = os.Stderr
+		}
+		rt = transport.NewTracer(rt, log, harRecorder)
+	}
+
+	transport.SetDefault(rt)
+}
+
+// writeTraceFile writes the requests recorded for --trace-file
+func writeTraceFile() {
+	if harRecorder == nil {
+		return
+	}
+	if err := harRecorder.WriteFile(traceFile); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+		return
+	}
+	if !IsQuiet() {
+		fmt.Fprintf(os.Stderr, "Wrote HTTP trace to %s\n", traceFile)
+	}
+}
+
+// transportOptions returns the transport settings of the top-level transport
+// section, overridden by those of the active profile
+func transportOptions() transport.Options {
+	settings := config.TransportSettings{
+		CABundle:   viper.GetString("transport.ca_bundle"),
+		ClientCert: viper.GetString("transport.client_cert"),
+		ClientKey:  viper.GetString("transport.client_key"),
+		ProxyURL:   viper.GetString("transport.proxy_url"),
+		NoProxy:    viper.GetString("transport.no_proxy"),
+		Pins:       viper.GetStringMapStringSlice("transport.pins"),
+	}
+
+	// Without a usable profile the command fails later with a clearer error
+	if profile, err := activeProfile(); err == nil {
+		settings = settings.Merge(profile.Transport)
+	}
+
+	return transport.Options{
+		CABundle:   settings.CABundle,
+		ClientCert: settings.ClientCert,
+		ClientKey:  settings.ClientKey,
+		ProxyURL:   settings.ProxyURL,
+		NoProxy:    settings.NoProxy,
+		Pins:       settings.Pins,
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/Bader-GmbH/iot-cli/internal/api"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var usageCmd = &cobra.Command{
@@ -29,12 +28,7 @@ func init() {
 }
 
 func runUsage(cmd *cobra.Command, args []string) error {
-	apiURL := viper.GetString("api_url")
-	if apiURL == "" {
-		apiURL = "https://api.iot.bader.solutions"
-	}
-
-	client, err := api.NewClient(apiURL)
+	client, err := newAPIClient()
 	if err != nil {
 		return err
 	}
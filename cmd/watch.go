@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/Bader-GmbH/iot-cli/internal/watch"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// errTimedOut is returned when the devices do not reach the --until state
+// within --timeout
+var errTimedOut = errors.New("timed out")
+
+var deviceWatchCmd = &cobra.Command{
+	Use:   "watch [device...]",
+	Short: "Follow the connection state of devices",
+	Long: `Follow the connection state of devices as it changes.
+
+On a terminal the device table is redrawn in place. Otherwise each change is
+written as a line of JSON with the event type (online, offline, approved or
+heartbeat_stale), the time and the device. --json selects the event lines on
+a terminal too.
+
+Changes are pushed by the platform where it supports it; otherwise the
+device list is polled every --interval.
+
+Without devices, the whole fleet is watched. Devices are given by ID, name,
+unique name prefix or name@group, or selected by group (@group) or with
+--group, --name, --where and -l (see 'iot device list --help' for queries
+and label selectors).
+
+With --until, watch exits as soon as all watched devices are in the given
+state, with exit code 8 when --timeout passes first and with exit code 1
+when it is interrupted.
+
+Examples:
+  iot device watch
+  iot device watch @line-1
+  iot device watch --where 'group=line-2 && name~"^press-"'
+  iot device watch press-01 --until online --timeout 5m
+  iot device watch | jq 'select(.type == "offline") | .device.name'`,
+	ValidArgsFunction: completeDevices,
+	RunE:              runDeviceWatch,
+}
+
+func init() {
+	deviceCmd.AddCommand(deviceWatchCmd)
+
+	addSelectorFlags(deviceWatchCmd)
+	deviceWatchCmd.Flags().String("until", "", "Exit once all devices are online, offline or approved")
+	deviceWatchCmd.Flags().Duration("timeout", 0, "Stop watching after this long (0 for no limit); a failure with --until")
+	deviceWatchCmd.Flags().Duration("interval", 5*time.Second, "How often to poll when the platform does not push changes")
+	deviceWatchCmd.Flags().Duration("stale-after", 2*time.Minute, "Report online devices without a heartbeat for this long (0 to disable)")
+}
+
+// deviceUpdate is what the platform reported: a full device listing, the
+// new state of a single device, or a failure to reach it
+type deviceUpdate struct {
+	devices []models.Device
+	err     error
+}
+
+func runDeviceWatch(cmd *cobra.Command, args []string) error {
+	untilFlag, _ := cmd.Flags().GetString("until")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	staleAfter, _ := cmd.Flags().GetDuration("stale-after")
+
+	var until watch.State
+	if untilFlag != "" {
+		var ok bool
+		if until, ok = watch.ParseState(untilFlag); !ok {
+			return fmt.Errorf("invalid --until %q: must be online, offline or approved", untilFlag)
+		}
+	}
+	if interval <= 0 || timeout < 0 || staleAfter < 0 {
+		return fmt.Errorf("--interval must be positive, --timeout and --stale-after must not be negative")
+	}
+
+	client, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The watched devices are fixed when watching starts
+	all, _ := cmd.Flags().GetBool("all")
+	accept, _, _ := newDeviceFilter("", "", all)
+	group, _ := cmd.Flags().GetString("group")
+	name, _ := cmd.Flags().GetString("name")
+	where, _ := cmd.Flags().GetString("where")
+	selector, _ := cmd.Flags().GetString("selector")
+	if len(args) > 0 || group != "" || name != "" || where != "" || selector != "" {
+		selected, err := selectDevices(ctx, cmd, client, args, allDevices(client))
+		if err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			return fmt.Errorf("no matching devices")
+		}
+		ids := make(map[string]bool, len(selected))
+		for _, d := range selected {
+			ids[d.ID] = true
+		}
+		accept = func(d *models.Device) bool { return ids[d.ID] }
+	}
+	tracker := watch.NewTracker(staleAfter, accept)
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	updates := make(chan deviceUpdate)
+	go followDevices(ctx, client, interval, updates)
+
+	live := !IsJSON() && term.IsTerminal(int(os.Stdout.Fd()))
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	listed := false
+	var lastErr error
+	for {
+		var events []watch.Event
+		select {
+		case <-ctx.Done():
+			// Only reaching the state counts as success for a waiting script
+			if until != "" {
+				return fmt.Errorf("%w while waiting for devices to be %s", errAborted, until)
+			}
+			return nil
+		case <-deadline:
+			if until != "" {
+				return fmt.Errorf("%w after %s waiting for devices to be %s", errTimedOut, timeout, until)
+			}
+			return nil
+		case update := <-updates:
+			if update.err != nil {
+				if !listed || errors.Is(update.err, api.ErrUnauthorized) || errors.Is(update.err, api.ErrForbidden) {
+					return fmt.Errorf("failed to watch devices: %w", update.err)
+				}
+				lastErr = update.err
+				if !live {
+					fmt.Fprintf(os.Stderr, "Warning: %v, retrying\n", update.err)
+				}
+				continue
+			}
+			lastErr = nil
+			listed = true
+			events = tracker.ObserveAll(update.devices, time.Now())
+		case now := <-ticker.C:
+			events = tracker.CheckStale(now)
+		}
+		if !listed {
+			continue
+		}
+
+		if live {
+			renderWatch(tracker, lastErr)
+		} else {
+			for _, e := range events {
+				if err := output.JSONLine(e); err != nil {
+					return err
+				}
+			}
+		}
+		if until != "" && tracker.All(until) {
+			return nil
+		}
+	}
+}
+
+// followDevices reports the devices to updates until ctx is done: first a
+// full listing, then each change pushed by the platform. Without a push
+// channel, the listing is repeated every interval; conditional requests keep
+// unchanged listings cheap. After the push channel drops, the devices are
+// listed again to catch up before reconnecting.
+func followDevices(ctx context.Context, client *api.Client, interval time.Duration, updates chan<- deviceUpdate) {
+	send := func(u deviceUpdate) bool {
+		select {
+		case updates <- u:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	poller := client.WithCache(api.CacheRevalidate)
+	push := true
+	for {
+		devices, err := poller.CollectDevices(ctx, api.DeviceQuery{})
+		if ctx.Err() != nil || !send(deviceUpdate{devices: devices, err: err}) {
+			return
+		}
+
+		if push && err == nil {
+			err := client.WatchDevices(ctx, func(d models.Device) {
+				send(deviceUpdate{devices: []models.Device{d}})
+			})
+			switch {
+			case ctx.Err() != nil:
+				return
+			case errors.Is(err, api.ErrEventsUnsupported):
+				push = false
+			case err != nil:
+				if !send(deviceUpdate{err: err}) {
+					return
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// renderWatch redraws the watched devices in place
+func renderWatch(tracker *watch.Tracker, lastErr error) {
+	devices := tracker.Devices()
+
+	// Move the cursor home and clear the screen
+	fmt.Print("\033[H\033[2J")
+	fmt.Printf("Watching %d device(s) at %s, press Ctrl+C to stop\n\n", len(devices), time.Now().Format("15:04:05"))
+
+	rows := make([][]string, 0, len(devices))
+	for _, d := range devices {
+		status := output.StatusIcon(d.Online) + " " + d.OnlineStatus()
+		if tracker.Stale(d.ID) {
+			status += ", heartbeat stale"
+		}
+		if d.Status != models.DeviceStatusApproved {
+			status += ", " + strings.ToLower(string(d.Status))
+		}
+		group := ""
+		if d.GroupName != nil {
+			group = *d.GroupName
+		}
+		rows = append(rows, []string{d.Name, status, group, d.LastSeenString()})
+	}
+	output.Table([]string{"NAME", "STATUS", "GROUP", "LAST SEEN"}, rows)
+
+	if lastErr != nil {
+		fmt.Printf("\nConnection problem, retrying: %v\n", lastErr)
+	}
+}
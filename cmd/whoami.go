@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/auth"
+	"github.com/Bader-GmbH/iot-cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the verified identity of the current login",
+	Long: `Show who you are logged in as.
+
+The identity, tenant and groups are read from the ID and access tokens after
+verifying their signatures against the user pool. The clock skew compares the
+local clock with the API server's; token lifetimes are checked against the
+local clock, so a large skew can make valid tokens look expired.`,
+	RunE: runWhoami,
+}
+
+func init() {
+	authCmd.AddCommand(whoamiCmd)
+}
+
+// tokenInfo describes the lifetime of one token
+type tokenInfo struct {
+	IssuedAt  time.Time `json:"issuedAt" yaml:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt" yaml:"expiresAt"`
+	ExpiresIn string    `json:"expiresIn" yaml:"expiresIn"`
+}
+
+// whoami is the output of 'iot auth whoami'
+type whoami struct {
+	Profile         string     `json:"profile" yaml:"profile"`
+	Method          string     `json:"method" yaml:"method"`
+	Subject         string     `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Username        string     `json:"username,omitempty" yaml:"username,omitempty"`
+	Email           string     `json:"email,omitempty" yaml:"email,omitempty"`
+	TenantID        string     `json:"tenantId,omitempty" yaml:"tenantId,omitempty"`
+	Groups          []string   `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Issuer          string     `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	IDToken         *tokenInfo `json:"idToken,omitempty" yaml:"idToken,omitempty"`
+	AccessToken     *tokenInfo `json:"accessToken,omitempty" yaml:"accessToken,omitempty"`
+	HasRefreshToken bool       `json:"hasRefreshToken" yaml:"hasRefreshToken"`
+	ClockSkew       string     `json:"clockSkew,omitempty" yaml:"clockSkew,omitempty"`
+}
+
+func runWhoami(cmd *cobra.Command, args []string) error {
+	profile, err := activeProfile()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	info := whoami{Profile: profile.Name}
+
+	if profile.APIKey != "" {
+		// API keys are opaque; the server knows who they belong to
+		info.Method = "api-key"
+	} else {
+		tokenStore, err := newTokenStore()
+		if err != nil {
+			return err
+		}
+
+		idClaims, accessClaims, err := tokenStore.Claims(ctx)
+		if err != nil {
+			return err
+		}
+
+		creds, err := tokenStore.Load()
+		if err != nil {
+			return err
+		}
+
+		info.Method = "login"
+		info.Subject = idClaims.Subject
+		info.Username = idClaims.Username
+		info.Email = idClaims.Email
+		info.TenantID = idClaims.TenantID
+		info.Groups = idClaims.Groups
+		info.Issuer = idClaims.Issuer
+		info.IDToken = newTokenInfo(idClaims)
+		info.AccessToken = newTokenInfo(accessClaims)
+		info.HasRefreshToken = creds.RefreshToken != ""
+	}
+
+	// Skew is informational; an unreachable API should not hide the identity
+	if skew, err := auth.ClockSkew(ctx, profile.APIURL); err == nil {
+		info.ClockSkew = skew.String()
+	} else if IsVerbose() {
+		fmt.Fprintf(os.Stderr, "Could not determine clock skew: %v\n", err)
+	}
+
+	if IsJSON() {
+		return outputJSON(info)
+	}
+	if IsYAML() {
+		return output.YAML(info)
+	}
+
+	rows := [][]string{
+		{"Profile", info.Profile},
+		{"Method", info.Method},
+	}
+	if info.Method == "login" {
+		groups := strings.Join(info.Groups, ", ")
+		if groups == "" {
+			groups = "-"
+		}
+		rows = append(rows,
+			[]string{"Email", info.Email},
+			[]string{"Username", info.Username},
+			[]string{"Subject", info.Subject},
+			[]string{"Tenant", info.TenantID},
+			[]string{"Groups", groups},
+			[]string{"Issuer", info.Issuer},
+			[]string{"ID token", formatTokenInfo(info.IDToken)},
+			[]string{"Access token", formatTokenInfo(info.AccessToken)},
+			[]string{"Refresh token", fmt.Sprintf("%t", info.HasRefreshToken)},
+		)
+	}
+	if info.ClockSkew != "" {
+		rows = append(rows, []string{"Clock skew", info.ClockSkew})
+	}
+
+	output.Table([]string{"FIELD", "VALUE"}, rows)
+	return nil
+}
+
+// newTokenInfo summarizes the lifetime of a verified token
+func newTokenInfo(c *auth.Claims) *tokenInfo {
+	return &tokenInfo{
+		IssuedAt:  c.IssuedAtTime(),
+		ExpiresAt: c.Expiry(),
+		ExpiresIn: time.Until(c.Expiry()).Round(time.Second).String(),
+	}
+}
+
+// formatTokenInfo formats a token lifetime for the table
+func formatTokenInfo(t *tokenInfo) string {
+	return fmt.Sprintf("expires %s (in %s)", t.ExpiresAt.Local().Format(time.RFC3339), t.ExpiresIn)
+}
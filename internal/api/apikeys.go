@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// APIKey represents a service account API key
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix,omitempty"`
+	Key        string     `json:"key,omitempty"` // Only returned when the key is created
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	CreatedBy  string     `json:"createdBy,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// CreateAPIKeyRequest describes a new API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreateAPIKey creates a new API key. The secret is only included in this response.
+func (c *Client) CreateAPIKey(ctx context.Context, req CreateAPIKeyRequest) (*APIKey, error) {
+	var key APIKey
+	if err := c.Post(ctx, "/api/auth/api-keys", req, &key, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys retrieves the API keys of the tenant
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	if err := c.Get(ctx, "/api/auth/api-keys", &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID string) error {
+	return c.Delete(ctx, "/api/auth/api-keys/"+keyID)
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/cache"
+)
+
+// CacheMode controls how the client uses cached GET responses
+type CacheMode uint
+
+const (
+	// CacheRevalidate ignores TTLs and asks the API every time. Unchanged
+	// responses are still answered with 304 Not Modified.
+	CacheRevalidate CacheMode = 1 << iota
+	// CacheStaleIfError answers from the cache, however old the entry, when
+	// the API cannot be reached
+	CacheStaleIfError
+)
+
+// CacheHeader is set on responses served from the cache: HIT for fresh
+// entries, REVALIDATED after a 304 Not Modified and STALE when the API
+// could not be reached
+const CacheHeader = "X-Iot-Cache"
+
+// maxCachedBody is the largest response body that is cached
+const maxCachedBody = 4 << 20
+
+// cacheRule is the TTL of the API paths matching pattern. A negative TTL
+// disables caching; a TTL of 0 stores the response but always revalidates it.
+type cacheRule struct {
+	pattern *regexp.Regexp
+	ttl     time.Duration
+}
+
+// cacheRules are matched in order against the request path
+var cacheRules = []cacheRule{
+	{regexp.MustCompile(`^/api/devices/[^/]+/files/download$`), -1},
+	{regexp.MustCompile(`^/api/registration-tokens(/.*)?$`), -1}, // Contain secrets
+	{regexp.MustCompile(`^/api/devices/[^/]+/files/`), 5 * time.Second},
+	{regexp.MustCompile(`^/api/devices/pending$`), 0},
+	{regexp.MustCompile(`^/api/devices(/[^/]+)?$`), 10 * time.Second},
+	{regexp.MustCompile(`^/api/tenants$`), 10 * time.Minute},
+	{regexp.MustCompile(`^/api/usage(/.*)?$`), 5 * time.Minute},
+}
+
+// cacheDependents are the scopes that changes to the API paths matching
+// pattern make outdated besides their own. Devices carry their group's name
+// and are listed by group, so group changes invalidate the device listings.
+var cacheDependents = []struct {
+	pattern *regexp.Regexp
+	scopes  []string
+}{
+	{regexp.MustCompile(`^/api/groups(/.*)?$`), []string{"/api/devices"}},
+}
+
+// cachedHeaders are the response headers kept in cache entries
+var cachedHeaders = []string{"Content-Type", "ETag", "Last-Modified", "Date"}
+
+// cacheTTL returns the TTL for an API path
+func cacheTTL(path string) time.Duration {
+	for _, rule := range cacheRules {
+		if rule.pattern.MatchString(path) {
+			return rule.ttl
+		}
+	}
+	return 0
+}
+
+// cacheScope groups the entries of a path with those of its sub-paths, so
+// that a change to a device also invalidates its file listings
+func cacheScope(path string) string {
+	segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 4)
+	if len(segments) > 3 {
+		segments = segments[:3]
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// WithCache returns a copy of the client that additionally applies the
+// given cache modes
+func (c *Client) WithCache(mode CacheMode) *Client {
+	clone := *c
+	clone.cacheMode |= mode
+	return &clone
+}
+
+// ClearCache removes the cached responses of the client's profile
+func (c *Client) ClearCache() error {
+	if c.cache == nil {
+		return nil
+	}
+	return c.cache.Clear()
+}
+
+// sendCached performs a GET request through the response cache. Fresh
+// entries are returned without contacting the API; otherwise the request is
+// made conditional on the cached entity tag or date.
+func (c *Client) sendCached(ctx context.Context, path, contentType string, opts ...RequestOption) (*http.Response, error) {
+	urlPath, _, _ := strings.Cut(path, "?")
+	ttl := cacheTTL(urlPath)
+	if ttl < 0 {
+		return c.transmit(ctx, http.MethodGet, path, nil, contentType, opts...)
+	}
+
+	scope := cacheScope(urlPath)
+	key, err := c.cacheKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, _ := c.cache.Get(scope, key)
+	if entry != nil && c.cacheMode&CacheRevalidate == 0 && entry.Fresh(ttl, time.Now()) {
+		return cachedResponse(entry, "HIT"), nil
+	}
+
+	if entry != nil {
+		if etag := entry.ETag(); etag != "" {
+			opts = append(opts, WithHeader("If-None-Match", etag))
+		}
+		if modified := entry.LastModified(); modified != "" {
+			opts = append(opts, WithHeader("If-Modified-Since", modified))
+		}
+	}
+
+	resp, err := c.transmit(ctx, http.MethodGet, path, nil, contentType, opts...)
+	if err != nil {
+		// A deadline running out counts as the API being unreachable
+		if entry != nil && c.cacheMode&CacheStaleIfError != 0 && !errors.Is(ctx.Err(), context.Canceled) {
+			return cachedResponse(entry, "STALE"), nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		entry.StoredAt = time.Now()
+		_ = c.cache.Put(scope, key, entry)
+		return cachedResponse(entry, "REVALIDATED"), nil
+
+	case resp.StatusCode == http.StatusOK && cacheable(resp):
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if len(body) > maxCachedBody {
+			// Too large to cache, hand out the rest of the stream
+			resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+			return resp, nil
+		}
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		stored := &cache.Entry{
+			URL:        resp.Request.URL.String(),
+			StatusCode: resp.StatusCode,
+			Header:     http.Header{},
+			Body:       body,
+			StoredAt:   time.Now(),
+		}
+		for _, name := range cachedHeaders {
+			if v := resp.Header.Get(name); v != "" {
+				stored.Header.Set(name, v)
+			}
+		}
+		_ = c.cache.Put(scope, key, stored)
+
+	case resp.StatusCode >= 500 && entry != nil && c.cacheMode&CacheStaleIfError != 0:
+		resp.Body.Close()
+		return cachedResponse(entry, "STALE"), nil
+	}
+
+	return resp, nil
+}
+
+// invalidateCache drops the cached responses a successful change to path
+// may have made outdated: those of its scope, of the collection above it
+// and of its dependent scopes
+func (c *Client) invalidateCache(path string) {
+	if c.cache == nil {
+		return
+	}
+	urlPath, _, _ := strings.Cut(path, "?")
+	scope := cacheScope(urlPath)
+	_ = c.cache.Invalidate(scope)
+	if i := strings.LastIndex(scope, "/"); i > 0 {
+		_ = c.cache.Invalidate(scope[:i])
+	}
+	for _, dep := range cacheDependents {
+		if dep.pattern.MatchString(urlPath) {
+			for _, s := range dep.scopes {
+				_ = c.cache.Invalidate(s)
+			}
+		}
+	}
+}
+
+// cacheKey identifies a response by URL and by the profile, identity and
+// tenant it was requested with, so that switching API keys or tenants never
+// serves another caller's data. It only reads the stored configuration and
+// credentials, never refreshing a token, so that cached responses remain
+// usable while the API cannot be reached.
+func (c *Client) cacheKey(path string) (string, error) {
+	identity := "user"
+	if c.apiKey != "" {
+		sum := sha256.Sum256([]byte(c.apiKey))
+		identity = "key:" + hex.EncodeToString(sum[:8])
+	}
+	tenantID, err := c.TenantID()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	}
+	return strings.Join([]string{http.MethodGet, c.baseURL + path, c.profile, identity, tenantID}, "\n"), nil
+}
+
+// cacheable reports whether a response may be stored. Only JSON responses
+// are; file contents are streamed.
+func cacheable(resp *http.Response) bool {
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// cachedResponse turns a cache entry into a response. The CacheHeader tells
+// how the entry was used.
+func cachedResponse(entry *cache.Entry, how string) *http.Response {
+	header := entry.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(CacheHeader, how)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+	}
+}
+
+// readCloser combines a reader with the closer of the underlying body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
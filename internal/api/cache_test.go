@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/auth"
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+)
+
+func TestCacheTTL(t *testing.T) {
+	tests := []struct {
+		path string
+		want time.Duration
+	}{
+		{"/api/devices", 10 * time.Second},
+		{"/api/devices/dev-1", 10 * time.Second},
+		{"/api/devices/dev-1/files/list", 5 * time.Second},
+		{"/api/devices/dev-1/files/download", -1},
+		{"/api/devices/pending", 0},
+		{"/api/tenants", 10 * time.Minute},
+		{"/api/usage/history", 5 * time.Minute},
+		{"/api/auth/cli-sessions", 0},
+		{"/api/registration-tokens", -1},
+		{"/api/registration-tokens/rt-1", -1},
+	}
+
+	for _, tt := range tests {
+		if got := cacheTTL(tt.path); got != tt.want {
+			t.Errorf("cacheTTL(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// newETagServer serves a device list with an ETag and answers conditional
+// requests with 304 Not Modified. It counts requests and 304 responses.
+func newETagServer(t *testing.T, requests, notModified *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method == http.MethodGet && r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`[{"id":"dev-1","name":"press-01"}]`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Cache(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := newETagServer(t, &requests, &notModified)
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	list := func(c *Client) {
+		t.Helper()
+		devices, err := c.ListDevices(ctx)
+		if err != nil {
+			t.Fatalf("ListDevices() unexpected error: %v", err)
+		}
+		if len(devices) != 1 || devices[0].Name != "press-01" {
+			t.Fatalf("ListDevices() = %+v, want press-01", devices)
+		}
+	}
+
+	// A fresh entry is served without a request
+	list(client)
+	list(client)
+	if got := requests.Load(); got != 1 {
+		t.Errorf("fresh entry: %d requests, want 1", got)
+	}
+
+	// Revalidation sends the ETag and reuses the entry on 304
+	list(client.WithCache(CacheRevalidate))
+	if got := notModified.Load(); got != 1 {
+		t.Errorf("revalidation: %d 304 responses, want 1", got)
+	}
+
+	// A change below the collection invalidates it
+	if err := client.Delete(ctx, "/api/devices/dev-1"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	before := requests.Load()
+	list(client)
+	if got := requests.Load() - before; got != 1 {
+		t.Errorf("after change: %d requests, want 1", got)
+	}
+	if got := notModified.Load(); got != 1 {
+		t.Errorf("after change: %d 304 responses, want no new ones", got)
+	}
+}
+
+func TestClient_CacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Client) error
+	}{
+		{name: "group membership", change: func(c *Client) error {
+			return c.Post(context.Background(), "/api/groups/g-1/devices", map[string][]string{"deviceIds": {"dev-1"}}, nil)
+		}},
+		{name: "group rename", change: func(c *Client) error {
+			return c.Patch(context.Background(), "/api/groups/g-1", map[string]string{"name": "line-9"}, nil)
+		}},
+		{name: "group deletion", change: func(c *Client) error {
+			return c.Delete(context.Background(), "/api/groups/g-1")
+		}},
+		{name: "labels", change: func(c *Client) error {
+			return c.Patch(context.Background(), "/api/devices/dev-1", map[string]LabelChanges{"labels": {"site": nil}}, nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests, notModified atomic.Int32
+			client := newTestClient(t, newETagServer(t, &requests, &notModified))
+			ctx := context.Background()
+
+			if _, err := client.ListDevices(ctx); err != nil {
+				t.Fatalf("ListDevices() unexpected error: %v", err)
+			}
+			if err := tt.change(client); err != nil {
+				t.Fatalf("change unexpected error: %v", err)
+			}
+			before := requests.Load()
+			if _, err := client.ListDevices(ctx); err != nil {
+				t.Fatalf("ListDevices() unexpected error: %v", err)
+			}
+			if got := requests.Load() - before; got != 1 {
+				t.Errorf("device list after the change: %d requests, want 1", got)
+			}
+		})
+	}
+}
+
+func TestClient_CacheStaleIfError(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := newETagServer(t, &requests, &notModified)
+	client := newTestClient(t, server)
+	client.retry.MaxRetries = 0
+	ctx := context.Background()
+
+	if _, err := client.ListDevices(ctx); err != nil {
+		t.Fatalf("ListDevices() unexpected error: %v", err)
+	}
+	server.Close()
+
+	if _, err := client.WithCache(CacheRevalidate).ListDevices(ctx); err == nil {
+		t.Error("ListDevices() without the API succeeded, want an error")
+	}
+
+	devices, err := client.WithCache(CacheRevalidate | CacheStaleIfError).ListDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListDevices() with CacheStaleIfError unexpected error: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Errorf("got %d devices from the cache, want 1", len(devices))
+	}
+}
+
+func TestClient_CacheOfflineWithExpiredToken(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" || r.Header.Get("X-Tenant-ID") != "tenant-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id":"dev-1","name":"press-01"}]`))
+	}))
+	defer server.Close()
+	// Refreshing the token fails, the user pool cannot be reached either
+	cognito := httptest.NewServer(http.NotFoundHandler())
+	cognito.Close()
+
+	writeCredentials := func(expiresAt time.Time) {
+		t.Helper()
+		path, err := config.GetCredentialsPath(config.DefaultProfileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(auth.Credentials{
+			AccessToken:  "access",
+			IDToken:      "id",
+			RefreshToken: "refresh",
+			ExpiresAt:    expiresAt,
+			TenantID:     "tenant-1",
+		})
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client, err := NewClient(&config.Profile{
+		Name:    config.DefaultProfileName,
+		APIURL:  server.URL,
+		Cognito: config.CognitoSettings{Region: "eu-central-1", ClientID: "client", Endpoint: cognito.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+	client.retry = RetryPolicy{}
+	ctx := context.Background()
+
+	writeCredentials(time.Now().Add(time.Hour))
+	if _, err := client.ListDevices(ctx); err != nil {
+		t.Fatalf("ListDevices() unexpected error: %v", err)
+	}
+	if _, err := client.ResolveDevice(ctx, "press-01"); err != nil {
+		t.Fatalf("ResolveDevice() unexpected error: %v", err)
+	}
+
+	// An hour later the access token has expired and the API is gone
+	writeCredentials(time.Now().Add(-time.Minute))
+	server.Close()
+
+	devices, err := client.WithCache(CacheRevalidate | CacheStaleIfError).ListDevices(ctx)
+	if err != nil {
+		t.Fatalf("ListDevices() with CacheStaleIfError unexpected error: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Errorf("got %d devices from the cache, want 1", len(devices))
+	}
+	ref, err := client.WithCache(CacheRevalidate).ResolveDevice(ctx, "press")
+	if err != nil {
+		t.Fatalf("ResolveDevice() offline unexpected error: %v", err)
+	}
+	if ref.ID != "dev-1" {
+		t.Errorf("ResolveDevice() = %s, want dev-1", ref.ID)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,76 +10,184 @@ import (
 	"time"
 
 	"github.com/Bader-GmbH/iot-cli/internal/auth"
+	"github.com/Bader-GmbH/iot-cli/internal/cache"
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
 )
 
 // Client is the API client for the Bader IoT Platform
 type Client struct {
+	profile    string
 	baseURL    string
+	apiKey     string
+	tenantID   string // Overrides the tenant from the login when set
+	retry      RetryPolicy
 	httpClient *http.Client
 	tokenStore *auth.TokenStore
+	cache      *cache.Store // Nil if responses are not cached
+	cacheMode  CacheMode
 }
 
-// NewClient creates a new API client
-func NewClient(baseURL string) (*Client, error) {
-	tokenStore, err := auth.NewTokenStore()
+// NewClient creates a new API client for the given profile
+func NewClient(profile *config.Profile) (*Client, error) {
+	tokenStore, err := auth.NewTokenStore(profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize token store: %w", err)
 	}
 
-	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	name := profile.Name
+	if name == "" {
+		name = config.DefaultProfileName
+	}
+
+	client := &Client{
+		profile:    name,
+		baseURL:    profile.WithDefaults().APIURL,
+		apiKey:     profile.APIKey,
+		tenantID:   profile.Tenant,
+		retry:      RetryPolicyFromProfile(profile),
+		httpClient: transport.NewClient(30 * time.Second),
 		tokenStore: tokenStore,
-	}, nil
+	}
+
+	if dir, err := config.GetCacheDir(name); err == nil {
+		client.cache = cache.Open(dir)
+	}
+	return client, nil
 }
 
 // doRequest performs an authenticated HTTP request
-func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
-	// Get access token
-	accessToken, err := c.tokenStore.GetAccessToken()
-	if err != nil {
-		return nil, fmt.Errorf("authentication required: %w", err)
+func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return c.send(ctx, method, path, body, "application/json", opts...)
+}
+
+// send performs an authenticated HTTP request with the given content type.
+// GET requests go through the response cache; successful changes invalidate
+// the cached responses below the changed path.
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader, contentType string, opts ...RequestOption) (*http.Response, error) {
+	if c.cache != nil && method == http.MethodGet && body == nil {
+		return c.sendCached(ctx, path, contentType, opts...)
 	}
 
-	// Get tenant ID
-	tenantID, err := c.tokenStore.GetTenantID()
-	if err != nil {
-		return nil, fmt.Errorf("tenant ID not found: %w", err)
+	resp, err := c.transmit(ctx, method, path, body, contentType, opts...)
+	if err == nil && method != http.MethodGet && method != http.MethodHead && resp.StatusCode < 300 {
+		c.invalidateCache(path)
 	}
+	return resp, err
+}
 
+// transmit performs an authenticated HTTP request. Transient failures are
+// retried according to the client's retry policy if the request can safely
+// be sent again.
+func (c *Client) transmit(ctx context.Context, method, path string, body io.Reader, contentType string, opts ...RequestOption) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	// Requests to such a URL fail the same way however often they are sent
+	if (req.URL.Scheme != "http" && req.URL.Scheme != "https") || req.URL.Host == "" {
+		return nil, fmt.Errorf("invalid API URL %q: expected http(s)://host", c.baseURL)
+	}
 
 	// Set headers
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("X-Tenant-ID", tenantID)
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", "iot-cli/1.0")
 	req.Header.Set("X-Client-Type", "cli")
+	for _, opt := range opts {
+		opt(req)
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	retryable := canRetry(req)
+	for attempt := 0; ; attempt++ {
+		// Auth headers are set per attempt, a long wait may outlive the access token
+		authHeaders, err := c.AuthHeaders(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range authHeaders {
+			req.Header[k] = v
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+		}
+
+		resp, err := c.httpClient.Do(req)
+		last := !retryable || attempt >= c.retry.MaxRetries
+
+		if err != nil {
+			if last || !isRetryableError(ctx, err) {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			if err := sleep(ctx, c.retry.backoff(attempt)); err != nil {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			continue
+		}
+
+		if last || !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := c.retry.backoff(attempt)
+		if wait, ok := retryAfter(resp); ok {
+			// Waiting longer than allowed is pointless, report the failure instead
+			if wait > c.retry.MaxDelay {
+				return resp, nil
+			}
+			delay = wait
+		}
+
+		resp.Body.Close()
+		if err := sleep(ctx, delay); err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
 	}
+}
 
-	return resp, nil
+// Do performs an authenticated request to any API path and returns the
+// response without checking its status code
+func (c *Client) Do(ctx context.Context, method, path string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	return c.doRequest(ctx, method, path, body, opts...)
 }
 
-// GetCredentials returns the access token and tenant ID for WebSocket auth
-func (c *Client) GetCredentials() (accessToken, tenantID string, err error) {
-	accessToken, err = c.tokenStore.GetAccessToken()
+// AuthHeaders returns the headers that authenticate a request. With an API
+// key configured the key is sent; otherwise the logged-in user's bearer
+// token and tenant ID are used. A selected tenant replaces the login's
+// tenant. The headers are also used for WebSocket auth.
+func (c *Client) AuthHeaders(ctx context.Context) (http.Header, error) {
+	headers := http.Header{}
+
+	if c.apiKey != "" {
+		headers.Set("X-API-Key", c.apiKey)
+		if c.tenantID != "" {
+			headers.Set("X-Tenant-ID", c.tenantID)
+		}
+		return headers, nil
+	}
+
+	// Get access token
+	accessToken, err := c.tokenStore.GetAccessToken(ctx)
 	if err != nil {
-		return "", "", err
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, err)
 	}
-	tenantID, err = c.tokenStore.GetTenantID()
+
+	// Get tenant ID
+	tenantID, err := c.TenantID()
 	if err != nil {
-		return "", "", err
+		return nil, fmt.Errorf("tenant ID not found: %w", err)
 	}
-	return accessToken, tenantID, nil
+
+	headers.Set("Authorization", "Bearer "+accessToken)
+	headers.Set("X-Tenant-ID", tenantID)
+	return headers, nil
+}
+
+// UsesAPIKey reports whether the client authenticates with an API key
+func (c *Client) UsesAPIKey() bool {
+	return c.apiKey != ""
 }
 
 // GetBaseURL returns the API base URL
@@ -94,13 +203,39 @@ func (c *Client) Get(ctx context.Context, path string, result interface{}) error
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("unauthorized: please run 'iot auth login'")
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Post performs an authenticated POST request with a JSON body. It is only
+// retried if an idempotency key is given with WithIdempotencyKey.
+func (c *Client) Post(ctx context.Context, path string, body, result interface{}, opts ...RequestOption) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	resp, err := c.doRequest(ctx, "POST", path, reader, opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusOK, http.StatusCreated); err != nil {
+		return err
 	}
 
 	if result != nil {
@@ -111,3 +246,45 @@ func (c *Client) Get(ctx context.Context, path string, result interface{}) error
 
 	return nil
 }
+
+// Patch performs an authenticated PATCH request with a JSON body. Like
+// Post, it is only retried with an idempotency key.
+func (c *Client) Patch(ctx context.Context, path string, body, result interface{}, opts ...RequestOption) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	resp, err := c.doRequest(ctx, "PATCH", path, bytes.NewReader(data), opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return err
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Delete performs an authenticated DELETE request
+func (c *Client) Delete(ctx context.Context, path string) error {
+	resp, err := c.doRequest(ctx, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusOK, http.StatusNoContent); err != nil {
+		return err
+	}
+
+	return nil
+}
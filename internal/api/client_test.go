@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+)
+
+// newTestClient creates a client for the given test server using an API key
+func newTestClient(t *testing.T, server *httptest.Server) *Client {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	client, err := NewClient(&config.Profile{
+		Name:   config.DefaultProfileName,
+		APIURL: server.URL,
+		APIKey: "test-key",
+	})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+
+	// Keep retries fast
+	client.retry = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	return client
+}
+
+func TestClient_APIKeyAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-API-Key"); got != "test-key" {
+			t.Errorf("X-API-Key = %q, want %q", got, "test-key")
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q, want no bearer token", got)
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	if _, err := client.ListDevices(context.Background()); err != nil {
+		t.Fatalf("ListDevices() unexpected error: %v", err)
+	}
+}
+
+func TestClient_WithTenant(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Tenant-ID"))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	for _, c := range []*Client{client, client.WithTenant("tenant-b"), client} {
+		if _, err := c.ListDevices(context.Background()); err != nil {
+			t.Fatalf("ListDevices() unexpected error: %v", err)
+		}
+	}
+
+	want := []string{"", "tenant-b", ""}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d: X-Tenant-ID = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
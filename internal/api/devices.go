@@ -1,16 +1,188 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/Bader-GmbH/iot-cli/pkg/models"
 )
 
+// DeviceQuery filters and pages a device listing. The filters are applied by
+// the server.
+type DeviceQuery struct {
+	Status    string // online or offline
+	Group     string // Group name
+	Name      string // Case-insensitive substring of the device name
+	PageSize  int    // Devices per request, 0 for the server default
+	PageToken string // Continue a previous listing
+	Limit     int    // Stop after this many devices, 0 for no limit
+
+	// Filter is applied by the client to each page; only devices it accepts
+	// are returned and count against the limit
+	Filter func(d *models.Device) bool
+}
+
+// DevicePage is one page of a device listing
+type DevicePage struct {
+	Devices       []models.Device `json:"items"`
+	NextPageToken string          `json:"nextPageToken,omitempty"`
+}
+
+// values encodes the query parameters of a page request
+func (q DeviceQuery) values(pageSize int, pageToken string) url.Values {
+	v := url.Values{}
+	if q.Status != "" {
+		v.Set("status", q.Status)
+	}
+	if q.Group != "" {
+		v.Set("group", q.Group)
+	}
+	if q.Name != "" {
+		v.Set("name", q.Name)
+	}
+	if pageSize > 0 {
+		v.Set("pageSize", strconv.Itoa(pageSize))
+	}
+	if pageToken != "" {
+		v.Set("pageToken", pageToken)
+	}
+	return v
+}
+
+// matches reports whether a device passes the query filters. It is only used
+// for servers that return unpaginated, unfiltered listings.
+func (q DeviceQuery) matches(d models.Device) bool {
+	switch q.Status {
+	case "online":
+		if !d.Online {
+			return false
+		}
+	case "offline":
+		if d.Online {
+			return false
+		}
+	}
+	if q.Group != "" && (d.GroupName == nil || !strings.EqualFold(*d.GroupName, q.Group)) {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(d.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	return true
+}
+
+// ListDevicePage retrieves a single page of devices
+func (c *Client) ListDevicePage(ctx context.Context, q DeviceQuery, pageSize int, pageToken string) (*DevicePage, error) {
+	path := "/api/devices"
+	if v := q.values(pageSize, pageToken); len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+
+	var raw json.RawMessage
+	if err := c.Get(ctx, path, &raw); err != nil {
+		return nil, err
+	}
+
+	var page DevicePage
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		// Older servers return every device at once and ignore the filters
+		var devices []models.Device
+		if err := json.Unmarshal(raw, &devices); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		for _, d := range devices {
+			if q.matches(d) {
+				page.Devices = append(page.Devices, d)
+			}
+		}
+		return &page, nil
+	}
+
+	if err := json.Unmarshal(raw, &page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &page, nil
+}
+
+// DevicePages iterates over the pages of a device listing, fetching each page
+// on demand. With a limit, no page is requested larger than the number of
+// devices still needed, so the last page's next page token continues exactly
+// after the limit, also when a filter drops devices.
+func (c *Client) DevicePages(ctx context.Context, q DeviceQuery) iter.Seq2[*DevicePage, error] {
+	return func(yield func(*DevicePage, error) bool) {
+		token := q.PageToken
+		seen := 0
+		for {
+			size := q.PageSize
+			if q.Limit > 0 {
+				if remaining := q.Limit - seen; size == 0 || remaining < size {
+					size = remaining
+				}
+			}
+
+			page, err := c.ListDevicePage(ctx, q, size, token)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if q.Filter != nil {
+				page.Devices = slices.DeleteFunc(page.Devices, func(d models.Device) bool { return !q.Filter(&d) })
+			}
+			if q.Limit > 0 && seen+len(page.Devices) > q.Limit {
+				page.Devices = page.Devices[:q.Limit-seen]
+			}
+			seen += len(page.Devices)
+
+			if !yield(page, nil) {
+				return
+			}
+			if page.NextPageToken == "" || page.NextPageToken == token || (q.Limit > 0 && seen >= q.Limit) {
+				return
+			}
+			token = page.NextPageToken
+		}
+	}
+}
+
+// Devices iterates over the devices matching the query, fetching pages as the
+// iteration advances
+func (c *Client) Devices(ctx context.Context, q DeviceQuery) iter.Seq2[models.Device, error] {
+	return func(yield func(models.Device, error) bool) {
+		for page, err := range c.DevicePages(ctx, q) {
+			if err != nil {
+				yield(models.Device{}, err)
+				return
+			}
+			for _, d := range page.Devices {
+				if !yield(d, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // ListDevices retrieves all devices
 func (c *Client) ListDevices(ctx context.Context) ([]models.Device, error) {
+	return c.CollectDevices(ctx, DeviceQuery{})
+}
+
+// CollectDevices retrieves every device matching the query
+func (c *Client) CollectDevices(ctx context.Context, q DeviceQuery) ([]models.Device, error) {
 	var devices []models.Device
-	if err := c.Get(ctx, "/api/devices", &devices); err != nil {
-		return nil, err
+	for d, err := range c.Devices(ctx, q) {
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
 	}
 	return devices, nil
 }
@@ -18,7 +190,7 @@ func (c *Client) ListDevices(ctx context.Context) ([]models.Device, error) {
 // GetDevice retrieves a single device by ID
 func (c *Client) GetDevice(ctx context.Context, deviceID string) (*models.Device, error) {
 	var device models.Device
-	if err := c.Get(ctx, "/api/devices/"+deviceID, &device); err != nil {
+	if err := c.Get(ctx, "/api/devices/"+url.PathEscape(deviceID), &device); err != nil {
 		return nil, err
 	}
 	return &device, nil
@@ -32,3 +204,35 @@ func (c *Client) ListPendingDevices(ctx context.Context) ([]models.Device, error
 	}
 	return devices, nil
 }
+
+// ApproveDevice admits a device waiting for approval to the tenant
+func (c *Client) ApproveDevice(ctx context.Context, deviceID string) (*models.Device, error) {
+	var device models.Device
+	if err := c.Post(ctx, "/api/devices/"+url.PathEscape(deviceID)+"/approve", nil, &device, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// RejectDevice refuses a device waiting for approval. The reason is shown
+// to other administrators.
+func (c *Client) RejectDevice(ctx context.Context, deviceID, reason string) (*models.Device, error) {
+	req := struct {
+		Reason string `json:"reason,omitempty"`
+	}{Reason: reason}
+
+	var device models.Device
+	if err := c.Post(ctx, "/api/devices/"+url.PathEscape(deviceID)+"/reject", req, &device, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// DecommissionDevice permanently retires a device. It can no longer connect.
+func (c *Client) DecommissionDevice(ctx context.Context, deviceID string) (*models.Device, error) {
+	var device models.Device
+	if err := c.Post(ctx, "/api/devices/"+url.PathEscape(deviceID)+"/decommission", nil, &device, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// newPagingServer serves n devices named dev-0 to dev-<n-1> in pages, using
+// the offset as page token
+func newPagingServer(t *testing.T, n int, requests *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RawQuery)
+
+		q := r.URL.Query()
+		size, _ := strconv.Atoi(q.Get("pageSize"))
+		if size == 0 {
+			size = 10
+		}
+		offset, _ := strconv.Atoi(q.Get("pageToken"))
+
+		var page DevicePage
+		for i := offset; i < n && i < offset+size; i++ {
+			page.Devices = append(page.Devices, models.Device{ID: strconv.Itoa(i), Name: "dev-" + strconv.Itoa(i)})
+		}
+		if offset+size < n {
+			page.NextPageToken = strconv.Itoa(offset + size)
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func TestClient_Devices(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     DeviceQuery
+		wantCount int
+		wantReqs  []string
+	}{
+		{
+			name:      "all pages",
+			query:     DeviceQuery{PageSize: 4},
+			wantCount: 10,
+			wantReqs:  []string{"pageSize=4", "pageSize=4&pageToken=4", "pageSize=4&pageToken=8"},
+		},
+		{
+			name:      "limit shrinks last page",
+			query:     DeviceQuery{PageSize: 4, Limit: 6},
+			wantCount: 6,
+			wantReqs:  []string{"pageSize=4", "pageSize=2&pageToken=4"},
+		},
+		{
+			name: "client filter counts against the limit",
+			query: DeviceQuery{PageSize: 4, Limit: 3, Filter: func(d *models.Device) bool {
+				id, _ := strconv.Atoi(d.ID)
+				return id%2 == 1
+			}},
+			wantCount: 3,
+			wantReqs:  []string{"pageSize=3", "pageSize=2&pageToken=3", "pageSize=1&pageToken=5"},
+		},
+		{
+			name:      "page token and filters",
+			query:     DeviceQuery{Status: "online", Group: "line 1", Name: "press", PageToken: "8"},
+			wantCount: 2,
+			wantReqs:  []string{"group=line+1&name=press&pageToken=8&status=online"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := newPagingServer(t, 10, &requests)
+			defer server.Close()
+
+			devices, err := newTestClient(t, server).CollectDevices(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("CollectDevices() unexpected error: %v", err)
+			}
+			if len(devices) != tt.wantCount {
+				t.Errorf("got %d devices, want %d", len(devices), tt.wantCount)
+			}
+			if len(requests) != len(tt.wantReqs) {
+				t.Fatalf("requests = %q, want %q", requests, tt.wantReqs)
+			}
+			for i := range requests {
+				if requests[i] != tt.wantReqs[i] {
+					t.Errorf("request %d = %q, want %q", i, requests[i], tt.wantReqs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClient_DevicePages_NextToken(t *testing.T) {
+	var requests []string
+	server := newPagingServer(t, 10, &requests)
+	defer server.Close()
+
+	var last *DevicePage
+	for page, err := range newTestClient(t, server).DevicePages(context.Background(), DeviceQuery{Limit: 3}) {
+		if err != nil {
+			t.Fatalf("DevicePages() unexpected error: %v", err)
+		}
+		last = page
+	}
+	if last == nil || last.NextPageToken != "3" {
+		t.Errorf("last page = %+v, want next page token %q", last, "3")
+	}
+}
+
+func TestClient_Devices_UnpaginatedServer(t *testing.T) {
+	group := "line-1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]models.Device{
+			{ID: "1", Name: "press-01", Online: true, GroupName: &group},
+			{ID: "2", Name: "press-02", Online: false, GroupName: &group},
+			{ID: "3", Name: "lathe-01", Online: true},
+		})
+	}))
+	defer server.Close()
+
+	devices, err := newTestClient(t, server).CollectDevices(context.Background(), DeviceQuery{Status: "online", Name: "PRESS"})
+	if err != nil {
+		t.Fatalf("CollectDevices() unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].ID != "1" {
+		t.Errorf("got %+v, want only device 1", devices)
+	}
+}
+
+func TestClient_DevicePaths(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		_, _ = w.Write([]byte(`{"id":"dev-1"}`))
+	}))
+	defer server.Close()
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	// A device ID must stay a single path segment
+	const id = "dev/../1"
+	calls := []func() error{
+		func() error { _, err := client.GetDevice(ctx, id); return err },
+		func() error { _, err := client.ApproveDevice(ctx, id); return err },
+		func() error { _, err := client.RejectDevice(ctx, id, ""); return err },
+		func() error { _, err := client.DecommissionDevice(ctx, id); return err },
+	}
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	want := []string{
+		"GET /api/devices/dev%2F..%2F1",
+		"POST /api/devices/dev%2F..%2F1/approve",
+		"POST /api/devices/dev%2F..%2F1/reject",
+		"POST /api/devices/dev%2F..%2F1/decommission",
+	}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", paths, want)
+	}
+}
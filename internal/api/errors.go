@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for the failure classes callers branch on. Errors returned
+// by the client match them with errors.Is.
+var (
+	ErrUnauthorized  = errors.New("authentication required")
+	ErrForbidden     = errors.New("permission denied")
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrDeviceOffline = errors.New("device is offline")
+)
+
+// Error codes sent by the API in the code field of error responses
+const (
+	CodeDeviceOffline = "DEVICE_OFFLINE"
+	CodeAlreadyExists = "ALREADY_EXISTS"
+)
+
+// maxErrorBody bounds how much of a non-JSON error body ends up in the message
+const maxErrorBody = 512
+
+// Error is an error response from the platform API
+type Error struct {
+	StatusCode int    // HTTP status; 0 for errors detected by the client
+	Code       string // Machine-readable error code, if the API sent one
+	Message    string
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		if e.StatusCode == http.StatusUnauthorized {
+			msg = "unauthorized: please run 'iot auth login'"
+		} else {
+			msg = "API error: " + strings.ToLower(http.StatusText(e.StatusCode))
+		}
+	}
+
+	var details []string
+	if e.StatusCode != 0 {
+		details = append(details, fmt.Sprintf("status %d", e.StatusCode))
+	}
+	if e.Code != "" {
+		details = append(details, "code "+e.Code)
+	}
+	if e.RequestID != "" {
+		details = append(details, "request ID "+e.RequestID)
+	}
+	if len(details) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s (%s)", msg, strings.Join(details, ", "))
+}
+
+// Is reports whether the error belongs to the class of a sentinel error
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.Code == CodeAlreadyExists
+	case ErrDeviceOffline:
+		return e.Code == CodeDeviceOffline
+	}
+	return false
+}
+
+// errorBody is the JSON error response of the API
+type errorBody struct {
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId"`
+}
+
+// checkResponse returns nil if the response has one of the expected status
+// codes, and otherwise an *Error built from the response
+func checkResponse(resp *http.Response, expected ...int) error {
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return newError(resp)
+}
+
+// newError builds an *Error from an error response, consuming its body
+func newError(resp *http.Response) *Error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return parseError(resp, data)
+}
+
+// ResponseError returns nil for a successful (2xx) response and otherwise an
+// *Error built from the response and its already read body
+func ResponseError(resp *http.Response, body []byte) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return parseError(resp, body)
+}
+
+// parseError builds an *Error from an error response and its body
+func parseError(resp *http.Response, data []byte) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = resp.Header.Get("X-Amzn-RequestId")
+	}
+
+	var body errorBody
+	if json.Unmarshal(data, &body) == nil {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Message
+		if apiErr.Message == "" {
+			apiErr.Message = body.Error
+		}
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+		return apiErr
+	}
+
+	msg := strings.TrimSpace(string(data))
+	if len(msg) > maxErrorBody {
+		msg = msg[:maxErrorBody] + "..."
+	}
+	apiErr.Message = msg
+	return apiErr
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    map[string]string
+		body      string
+		want      error
+		wantCode  string
+		wantMsg   string
+		wantReqID string
+	}{
+		{
+			name:    "unauthorized",
+			status:  http.StatusUnauthorized,
+			want:    ErrUnauthorized,
+			wantMsg: "",
+		},
+		{
+			name:      "not found with JSON body",
+			status:    http.StatusNotFound,
+			header:    map[string]string{"X-Request-ID": "req-1"},
+			body:      `{"code":"DEVICE_NOT_FOUND","message":"device not found"}`,
+			want:      ErrNotFound,
+			wantCode:  "DEVICE_NOT_FOUND",
+			wantMsg:   "device not found",
+			wantReqID: "req-1",
+		},
+		{
+			name:     "conflict by code",
+			status:   http.StatusBadRequest,
+			body:     `{"code":"ALREADY_EXISTS","message":"directory exists"}`,
+			want:     ErrConflict,
+			wantCode: CodeAlreadyExists,
+			wantMsg:  "directory exists",
+		},
+		{
+			name:      "device offline",
+			status:    http.StatusServiceUnavailable,
+			body:      `{"code":"DEVICE_OFFLINE","error":"Device is offline","requestId":"req-2"}`,
+			want:      ErrDeviceOffline,
+			wantCode:  CodeDeviceOffline,
+			wantMsg:   "Device is offline",
+			wantReqID: "req-2",
+		},
+		{
+			name:    "plain text body",
+			status:  http.StatusConflict,
+			body:    "already exists\n",
+			want:    ErrConflict,
+			wantMsg: "already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server)
+			_, err := client.GetDevice(context.Background(), "dev-1")
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("GetDevice() error = %v, want errors.Is(%v)", err, tt.want)
+			}
+
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("GetDevice() error = %T, want *Error", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Code != tt.wantCode || apiErr.Message != tt.wantMsg || apiErr.RequestID != tt.wantReqID {
+				t.Errorf("GetDevice() error = %+v", apiErr)
+			}
+		})
+	}
+}
+
+func TestError_IsOnlyMatchesItsClass(t *testing.T) {
+	err := &Error{StatusCode: http.StatusNotFound}
+	for _, sentinel := range []error{ErrUnauthorized, ErrForbidden, ErrConflict, ErrDeviceOffline} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("errors.Is(404, %v) = true, want false", sentinel)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// ErrEventsUnsupported is returned by WatchDevices when the server has no
+// device event stream. Callers fall back to polling.
+var ErrEventsUnsupported = errors.New("device event stream not supported")
+
+// maxEventSize bounds a single server-sent event
+const maxEventSize = 1 << 20
+
+// WatchDevices subscribes to the device event stream and calls handle with
+// the new state of each device that changes. It blocks until the stream ends,
+// which it may do at any time; the caller reconnects. Events missed while
+// disconnected are not replayed.
+func (c *Client) WatchDevices(ctx context.Context, handle func(models.Device)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/devices/events", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", "iot-cli/1.0")
+	req.Header.Set("X-Client-Type", "cli")
+	authHeaders, err := c.AuthHeaders(ctx)
+	if err != nil {
+		return err
+	}
+	for k, v := range authHeaders {
+		req.Header[k] = v
+	}
+
+	// The stream stays open, so the client's request timeout must not apply
+	stream := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := stream.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotAcceptable, http.StatusNotImplemented:
+		return ErrEventsUnsupported
+	default:
+		return newError(resp)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		return ErrEventsUnsupported
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+			continue
+		}
+
+		// A blank line dispatches the event; other event types are ignored
+		if (event == "" || event == "device") && len(data) > 0 {
+			var d models.Device
+			if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &d); err != nil {
+				return fmt.Errorf("failed to decode device event: %w", err)
+			}
+			handle(d)
+		}
+		event, data = "", nil
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("device event stream failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+func TestClient_WatchDevices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			http.Error(w, "", http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(": keep-alive\n\n" +
+			"event: device\ndata: {\"id\":\"dev-1\",\"online\":false}\n\n" +
+			"event: tenant\ndata: {\"id\":\"sandbox\"}\n\n" +
+			"data: {\"id\":\"dev-2\",\n" +
+			"data: \"online\":true}\n\n"))
+	}))
+	defer server.Close()
+
+	var got []models.Device
+	err := newTestClient(t, server).WatchDevices(context.Background(), func(d models.Device) {
+		got = append(got, d)
+	})
+	if err != nil {
+		t.Fatalf("WatchDevices() unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "dev-1" || got[0].Online || got[1].ID != "dev-2" || !got[1].Online {
+		t.Errorf("WatchDevices() events = %+v, want dev-1 offline and dev-2 online", got)
+	}
+}
+
+func TestClient_WatchDevicesUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			},
+		},
+		{
+			name: "not an event stream",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"id":"events"}`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			err := newTestClient(t, server).WatchDevices(context.Background(), func(models.Device) {})
+			if !errors.Is(err, ErrEventsUnsupported) {
+				t.Errorf("WatchDevices() error = %v, want ErrEventsUnsupported", err)
+			}
+		})
+	}
+}
@@ -52,20 +52,9 @@ func (c *Client) DownloadFile(ctx context.Context, deviceID, path string) (io.Re
 		return nil, 0, err
 	}
 
-	if resp.StatusCode == http.StatusUnauthorized {
+	if err := checkResponse(resp, http.StatusOK); err != nil {
 		resp.Body.Close()
-		return nil, 0, fmt.Errorf("unauthorized: please run 'iot auth login'")
-	}
-
-	if resp.StatusCode == http.StatusNotFound {
-		resp.Body.Close()
-		return nil, 0, fmt.Errorf("file not found: %s", path)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, 0, fmt.Errorf("download failed (status %d): %s", resp.StatusCode, string(body))
+		return nil, 0, fmt.Errorf("download of %s failed: %w", path, err)
 	}
 
 	return resp.Body, resp.ContentLength, nil
@@ -93,19 +82,15 @@ func (c *Client) UploadFile(ctx context.Context, deviceID, path string, content
 	}
 
 	// Create request
-	resp, err := c.doMultipartRequest(ctx, "POST", endpoint, &buf, writer.FormDataContentType())
+	// The key makes the upload safe to retry
+	resp, err := c.doMultipartRequest(ctx, "POST", endpoint, &buf, writer.FormDataContentType(), WithIdempotencyKey(NewIdempotencyKey()))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("unauthorized: please run 'iot auth login'")
-	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed (status %d): %s", resp.StatusCode, string(body))
+	if err := checkResponse(resp, http.StatusOK, http.StatusCreated); err != nil {
+		return fmt.Errorf("upload of %s failed: %w", path, err)
 	}
 
 	return nil
@@ -115,56 +100,26 @@ func (c *Client) UploadFile(ctx context.Context, deviceID, path string, content
 func (c *Client) MkdirOnDevice(ctx context.Context, deviceID, path string) error {
 	endpoint := fmt.Sprintf("/api/devices/%s/files/mkdir?path=%s", deviceID, url.QueryEscape(path))
 
-	resp, err := c.doRequest(ctx, "POST", endpoint, nil)
+	resp, err := c.doRequest(ctx, "POST", endpoint, nil, WithIdempotencyKey(NewIdempotencyKey()))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("unauthorized: please run 'iot auth login'")
-	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("mkdir failed (status %d): %s", resp.StatusCode, string(body))
+	if err := checkResponse(resp, http.StatusOK, http.StatusCreated); err != nil {
+		return fmt.Errorf("mkdir of %s failed: %w", path, err)
 	}
 
 	return nil
 }
 
 // doMultipartRequest performs an authenticated multipart request
-func (c *Client) doMultipartRequest(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
-	accessToken, err := c.tokenStore.GetAccessToken()
-	if err != nil {
-		return nil, fmt.Errorf("authentication required: %w", err)
-	}
-
-	tenantID, err := c.tokenStore.GetTenantID()
-	if err != nil {
-		return nil, fmt.Errorf("tenant ID not found: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("X-Tenant-ID", tenantID)
-	req.Header.Set("Content-Type", contentType)
-	req.Header.Set("User-Agent", "iot-cli/1.0")
-	req.Header.Set("X-Client-Type", "cli")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-
-	return resp, nil
+func (c *Client) doMultipartRequest(ctx context.Context, method, path string, body io.Reader, contentType string, opts ...RequestOption) (*http.Response, error) {
+	return c.send(ctx, method, path, body, contentType, opts...)
 }
 
-// CheckDeviceOnline checks if a device is online and returns an error if not
+// CheckDeviceOnline checks if a device is online and returns an error
+// matching ErrDeviceOffline if not
 func (c *Client) CheckDeviceOnline(ctx context.Context, deviceID string) error {
 	endpoint := fmt.Sprintf("/api/devices/%s", deviceID)
 
@@ -178,7 +133,7 @@ func (c *Client) CheckDeviceOnline(ctx context.Context, deviceID string) error {
 	}
 
 	if !device.Online {
-		return fmt.Errorf("device %q is offline", device.Name)
+		return &Error{Code: CodeDeviceOffline, Message: fmt.Sprintf("device %q is offline", device.Name)}
 	}
 
 	return nil
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Group is a named set of devices, e.g. a production line
+type Group struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	DeviceCount int        `json:"deviceCount"`
+	OnlineCount int        `json:"onlineCount"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+}
+
+// CreateGroupRequest describes a new group
+type CreateGroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ListGroups retrieves the groups of the tenant with their member counts
+func (c *Client) ListGroups(ctx context.Context) ([]Group, error) {
+	var groups []Group
+	if err := c.Get(ctx, "/api/groups", &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetGroup retrieves a group by ID
+func (c *Client) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+	var group Group
+	if err := c.Get(ctx, "/api/groups/"+url.PathEscape(groupID), &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ResolveGroup finds a group by ID or by name, ignoring case
+func (c *Client) ResolveGroup(ctx context.Context, ref string) (*Group, error) {
+	groups, err := c.WithCache(CacheStaleIfError).ListGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	for i := range groups {
+		if groups[i].ID == ref {
+			return &groups[i], nil
+		}
+	}
+	for i := range groups {
+		if strings.EqualFold(groups[i].Name, ref) {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no group with ID or name %q", ErrNotFound, ref)
+}
+
+// CreateGroup creates a group
+func (c *Client) CreateGroup(ctx context.Context, req CreateGroupRequest) (*Group, error) {
+	var group Group
+	if err := c.Post(ctx, "/api/groups", req, &group, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// RenameGroup changes the name of a group
+func (c *Client) RenameGroup(ctx context.Context, groupID, name string) (*Group, error) {
+	req := struct {
+		Name string `json:"name"`
+	}{Name: name}
+
+	var group Group
+	if err := c.Patch(ctx, "/api/groups/"+url.PathEscape(groupID), req, &group, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return nil, err
+	}
+	// Every device of the group carries its name
+	_ = c.ClearCache()
+	return &group, nil
+}
+
+// DeleteGroup deletes a group. Its devices are left without a group.
+func (c *Client) DeleteGroup(ctx context.Context, groupID string) error {
+	if err := c.Delete(ctx, "/api/groups/"+url.PathEscape(groupID)); err != nil {
+		return err
+	}
+	_ = c.ClearCache()
+	return nil
+}
+
+// AddDevicesToGroup moves devices into a group. A device belongs to at most
+// one group, so it leaves its previous group.
+func (c *Client) AddDevicesToGroup(ctx context.Context, groupID string, deviceIDs []string) error {
+	req := struct {
+		DeviceIDs []string `json:"deviceIds"`
+	}{DeviceIDs: deviceIDs}
+
+	if err := c.Post(ctx, "/api/groups/"+url.PathEscape(groupID)+"/devices", req, nil, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return err
+	}
+	for _, id := range deviceIDs {
+		c.invalidateCache("/api/devices/" + id)
+	}
+	return nil
+}
+
+// RemoveDeviceFromGroup takes a device out of a group
+func (c *Client) RemoveDeviceFromGroup(ctx context.Context, groupID, deviceID string) error {
+	if err := c.Delete(ctx, "/api/groups/"+url.PathEscape(groupID)+"/devices/"+url.PathEscape(deviceID)); err != nil {
+		return err
+	}
+	c.invalidateCache("/api/devices/" + deviceID)
+	return nil
+}
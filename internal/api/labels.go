@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// maxLabelLength bounds label keys and values
+const maxLabelLength = 63
+
+// Label keys and non-empty values are alphanumerics with -, _ and . inside.
+// Keys may have a DNS-like prefix ending in /, e.g. example.com/site.
+var (
+	labelKeyPattern   = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+)
+
+// LabelChanges sets labels to new values; a nil value removes the label
+type LabelChanges map[string]*string
+
+// ParseLabelChanges parses label arguments: key=value sets a label, key-
+// removes it. Each key may be changed only once.
+func ParseLabelChanges(args []string) (LabelChanges, error) {
+	changes := make(LabelChanges, len(args))
+	for _, arg := range args {
+		if key, ok := strings.CutSuffix(arg, "-"); ok && !strings.Contains(arg, "=") {
+			if err := ValidateLabelKey(key); err != nil {
+				return nil, err
+			}
+			if _, seen := changes[key]; seen {
+				return nil, fmt.Errorf("label %s is given more than once", key)
+			}
+			changes[key] = nil
+			continue
+		}
+
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q: use key=value to set a label or key- to remove it", arg)
+		}
+		if err := ValidateLabelKey(key); err != nil {
+			return nil, err
+		}
+		if err := ValidateLabelValue(value); err != nil {
+			return nil, fmt.Errorf("invalid value for label %s: %w", key, err)
+		}
+		if _, seen := changes[key]; seen {
+			return nil, fmt.Errorf("label %s is given more than once", key)
+		}
+		changes[key] = &value
+	}
+	return changes, nil
+}
+
+// ValidateLabelKey checks a label key
+func ValidateLabelKey(key string) error {
+	name := key[strings.LastIndex(key, "/")+1:]
+	if len(name) > maxLabelLength || !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q: use up to %d letters, digits, -, _ and ., starting and ending with a letter or digit", key, maxLabelLength)
+	}
+	return nil
+}
+
+// ValidateLabelValue checks a label value. Values may be empty.
+func ValidateLabelValue(value string) error {
+	if value != "" && (len(value) > maxLabelLength || !labelValuePattern.MatchString(value)) {
+		return fmt.Errorf("%q: use up to %d letters, digits, -, _ and ., starting and ending with a letter or digit", value, maxLabelLength)
+	}
+	return nil
+}
+
+// UpdateDeviceLabels applies label changes to a device, leaving its other
+// labels as they are
+func (c *Client) UpdateDeviceLabels(ctx context.Context, deviceID string, changes LabelChanges) (*models.Device, error) {
+	body := map[string]LabelChanges{"labels": changes}
+	var device models.Device
+	if err := c.Patch(ctx, "/api/devices/"+url.PathEscape(deviceID), body, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+func TestParseLabelChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]string // "-" marks a removal
+		wantErr bool
+	}{
+		{name: "set", args: []string{"site=hamburg", "hw=rev3"}, want: map[string]string{"site": "hamburg", "hw": "rev3"}},
+		{name: "remove", args: []string{"site-"}, want: map[string]string{"site": "-"}},
+		{name: "empty value", args: []string{"spare="}, want: map[string]string{"spare": ""}},
+		{name: "value ending in dash", args: []string{"hw=rev-3"}, want: map[string]string{"hw": "rev-3"}},
+		{name: "prefixed key", args: []string{"example.com/site=hamburg"}, want: map[string]string{"example.com/site": "hamburg"}},
+		{name: "missing value", args: []string{"site"}, wantErr: true},
+		{name: "bad key", args: []string{"-site=hamburg"}, wantErr: true},
+		{name: "bad value", args: []string{"site=ham burg"}, wantErr: true},
+		{name: "bad removal", args: []string{"si te-"}, wantErr: true},
+		{name: "duplicate", args: []string{"site=hamburg", "site=bremen"}, wantErr: true},
+		{name: "set and remove", args: []string{"site=hamburg", "site-"}, wantErr: true},
+		{name: "remove and set", args: []string{"site-", "site=hamburg"}, wantErr: true},
+		{name: "removed twice", args: []string{"site-", "site-"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLabelChanges(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLabelChanges(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseLabelChanges(%q) = %d changes, want %d", tt.args, len(got), len(tt.want))
+			}
+			for key, want := range tt.want {
+				value, ok := got[key]
+				switch {
+				case !ok:
+					t.Errorf("missing change for %s", key)
+				case want == "-" && value != nil:
+					t.Errorf("%s = %q, want removal", key, *value)
+				case want != "-" && (value == nil || *value != want):
+					t.Errorf("%s = %v, want %q", key, value, want)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_UpdateDeviceLabels(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		var body json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotBody = string(body)
+		_ = json.NewEncoder(w).Encode(models.Device{ID: "dev-1", Labels: map[string]string{"hw": "rev3"}})
+	}))
+	defer server.Close()
+
+	changes, err := ParseLabelChanges([]string{"hw=rev3", "site-"})
+	if err != nil {
+		t.Fatalf("ParseLabelChanges() unexpected error: %v", err)
+	}
+	device, err := newTestClient(t, server).UpdateDeviceLabels(context.Background(), "dev-1", changes)
+	if err != nil {
+		t.Fatalf("UpdateDeviceLabels() unexpected error: %v", err)
+	}
+
+	if gotMethod != http.MethodPatch || gotPath != "/api/devices/dev-1" {
+		t.Errorf("request = %s %s, want PATCH /api/devices/dev-1", gotMethod, gotPath)
+	}
+	if want := `{"labels":{"hw":"rev3","site":null}}`; gotBody != want {
+		t.Errorf("body = %s, want %s", gotBody, want)
+	}
+	if device.Labels["hw"] != "rev3" {
+		t.Errorf("labels = %v, want hw=rev3", device.Labels)
+	}
+}
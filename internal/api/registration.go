@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Registration token states, as reported by RegistrationToken.State
+const (
+	TokenActive  = "active"
+	TokenExpired = "expired"
+	TokenRevoked = "revoked"
+	TokenUsedUp  = "used up"
+)
+
+// RegistrationToken lets new devices enroll themselves. Devices that register
+// with it wait for approval, in the token's group if it has one.
+type RegistrationToken struct {
+	ID        string     `json:"id"`
+	Token     string     `json:"token,omitempty"` // Only returned when the token is created or fetched by ID
+	GroupID   *string    `json:"groupId,omitempty"`
+	GroupName *string    `json:"groupName,omitempty"`
+	MaxUses   int        `json:"maxUses,omitempty"` // 0 for unlimited
+	Uses      int        `json:"uses"`
+	CreatedAt time.Time  `json:"createdAt"`
+	CreatedBy string     `json:"createdBy,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// State returns whether devices can still register with the token at the
+// given time, or why not
+func (t *RegistrationToken) State(now time.Time) string {
+	switch {
+	case t.RevokedAt != nil:
+		return TokenRevoked
+	case t.ExpiresAt != nil && !now.Before(*t.ExpiresAt):
+		return TokenExpired
+	case t.MaxUses > 0 && t.Uses >= t.MaxUses:
+		return TokenUsedUp
+	default:
+		return TokenActive
+	}
+}
+
+// CreateRegistrationTokenRequest describes a new registration token
+type CreateRegistrationTokenRequest struct {
+	GroupID   *string    `json:"groupId,omitempty"`
+	MaxUses   int        `json:"maxUses,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreateRegistrationToken creates a registration token
+func (c *Client) CreateRegistrationToken(ctx context.Context, req CreateRegistrationTokenRequest) (*RegistrationToken, error) {
+	var token RegistrationToken
+	if err := c.Post(ctx, "/api/registration-tokens", req, &token, WithIdempotencyKey(NewIdempotencyKey())); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListRegistrationTokens retrieves the registration tokens of the tenant,
+// without their secrets
+func (c *Client) ListRegistrationTokens(ctx context.Context) ([]RegistrationToken, error) {
+	var tokens []RegistrationToken
+	if err := c.Get(ctx, "/api/registration-tokens", &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetRegistrationToken retrieves a registration token including its secret
+func (c *Client) GetRegistrationToken(ctx context.Context, tokenID string) (*RegistrationToken, error) {
+	var token RegistrationToken
+	if err := c.Get(ctx, "/api/registration-tokens/"+url.PathEscape(tokenID), &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeRegistrationToken revokes a registration token. Devices that already
+// registered with it are not affected.
+func (c *Client) RevokeRegistrationToken(ctx context.Context, tokenID string) error {
+	return c.Delete(ctx, "/api/registration-tokens/"+url.PathEscape(tokenID))
+}
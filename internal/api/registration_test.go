@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegistrationToken_State(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		token RegistrationToken
+		want  string
+	}{
+		{name: "unlimited", token: RegistrationToken{Uses: 100}, want: TokenActive},
+		{name: "uses left", token: RegistrationToken{Uses: 4, MaxUses: 5, ExpiresAt: &future}, want: TokenActive},
+		{name: "used up", token: RegistrationToken{Uses: 5, MaxUses: 5}, want: TokenUsedUp},
+		{name: "expired", token: RegistrationToken{ExpiresAt: &past}, want: TokenExpired},
+		{name: "expires now", token: RegistrationToken{ExpiresAt: &now}, want: TokenExpired},
+		{name: "revoked wins", token: RegistrationToken{Uses: 5, MaxUses: 5, ExpiresAt: &past, RevokedAt: &past}, want: TokenRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.token.State(now); got != tt.want {
+				t.Errorf("State() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_RegistrationTokenPaths(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+		_, _ = w.Write([]byte(`{"id":"rt-1"}`))
+	}))
+	defer server.Close()
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	// Token IDs are typed by the user and must stay a single path segment
+	if _, err := client.GetRegistrationToken(ctx, "rt-1/../x"); err != nil {
+		t.Fatalf("GetRegistrationToken() unexpected error: %v", err)
+	}
+	if err := client.RevokeRegistrationToken(ctx, "rt 1"); err != nil {
+		t.Fatalf("RevokeRegistrationToken() unexpected error: %v", err)
+	}
+
+	want := []string{"GET /api/registration-tokens/rt-1%2F..%2Fx", "DELETE /api/registration-tokens/rt%201"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("requests = %q, want %q", paths, want)
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/cache"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// deviceIndexTTL is how long the name index is trusted before it is rebuilt
+// from the device list
+const deviceIndexTTL = 10 * time.Minute
+
+// deviceIndexScope is the cache scope of the device list
+const deviceIndexScope = "/api/devices"
+
+// DeviceRef identifies a resolved device
+type DeviceRef struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Group string `json:"group,omitempty"`
+}
+
+// String returns the device's name, qualified with its group if it has one
+func (r DeviceRef) String() string {
+	if r.Group == "" {
+		return r.Name
+	}
+	return r.Name + "@" + r.Group
+}
+
+// AmbiguousDeviceError is returned when a device reference matches more than
+// one device
+type AmbiguousDeviceError struct {
+	Ref        string
+	Candidates []DeviceRef
+}
+
+func (e *AmbiguousDeviceError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q matches %d devices, use the ID, full name or name@group:", e.Ref, len(e.Candidates))
+	for _, c := range e.Candidates {
+		fmt.Fprintf(&b, "\n  %s (%s)", c.String(), c.ID)
+	}
+	return b.String()
+}
+
+// deviceIndex is the cached name to ID index of a tenant's devices
+type deviceIndex struct {
+	Devices []DeviceRef `json:"devices"`
+}
+
+// ResolveDevice finds the device a reference given on the command line
+// stands for. The reference is an exact device ID, an exact name, a unique
+// name prefix or name@group. Names are looked up in a cached index, which
+// is rebuilt from the device list when it is old or has no match.
+func (c *Client) ResolveDevice(ctx context.Context, ref string) (*DeviceRef, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("device cannot be empty")
+	}
+
+	index, fresh := c.loadDeviceIndex(ctx)
+	if index != nil {
+		match, err := index.resolve(ref)
+		if fresh && (match != nil || err != nil) {
+			return match, err
+		}
+	}
+
+	rebuilt, listErr := c.rebuildDeviceIndex(ctx)
+	if listErr == nil {
+		index = rebuilt
+	}
+	if index != nil {
+		if match, err := index.resolve(ref); match != nil || err != nil {
+			return match, err
+		}
+	}
+
+	// The list may not show every device, so fall back to the reference as an ID
+	device, err := c.GetDevice(ctx, ref)
+	if err == nil {
+		return RefOf(device), nil
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: no device with ID or name %q", ErrNotFound, ref)
+	}
+	if listErr != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", listErr)
+	}
+	return nil, err
+}
+
+// resolve matches a reference against the index. It returns nil without an
+// error if nothing matches.
+func (idx *deviceIndex) resolve(ref string) (*DeviceRef, error) {
+	for i := range idx.Devices {
+		if idx.Devices[i].ID == ref {
+			return &idx.Devices[i], nil
+		}
+	}
+
+	name, group, qualified := strings.Cut(ref, "@")
+	candidates := idx.Devices
+	if qualified {
+		candidates = nil
+		for _, d := range idx.Devices {
+			if strings.EqualFold(d.Group, group) {
+				candidates = append(candidates, d)
+			}
+		}
+	}
+
+	// Exact names win over prefixes
+	var exact, prefixed []DeviceRef
+	for _, d := range candidates {
+		switch {
+		case d.Name == name:
+			exact = append(exact, d)
+		case strings.HasPrefix(d.Name, name):
+			prefixed = append(prefixed, d)
+		}
+	}
+	matches := exact
+	if len(matches) == 0 {
+		matches = prefixed
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &matches[0], nil
+	default:
+		sort.Slice(matches, func(i, j int) bool { return matches[i].String() < matches[j].String() })
+		return nil, &AmbiguousDeviceError{Ref: ref, Candidates: matches}
+	}
+}
+
+// loadDeviceIndex returns the cached index and whether it is fresh. It
+// returns nil if there is none.
+func (c *Client) loadDeviceIndex(ctx context.Context) (*deviceIndex, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	key, err := c.cacheKey("#device-index")
+	if err != nil {
+		return nil, false
+	}
+	entry, _ := c.cache.Get(deviceIndexScope, key)
+	if entry == nil {
+		return nil, false
+	}
+
+	var index deviceIndex
+	if err := json.Unmarshal(entry.Body, &index); err != nil {
+		return nil, false
+	}
+	return &index, entry.Fresh(deviceIndexTTL, time.Now()) && c.cacheMode&CacheRevalidate == 0
+}
+
+// rebuildDeviceIndex builds the index from the device list and stores it.
+// The index lives in the scope of the device list, so changes to devices
+// drop it along with the list.
+func (c *Client) rebuildDeviceIndex(ctx context.Context) (*deviceIndex, error) {
+	devices, err := c.WithCache(CacheStaleIfError).CollectDevices(ctx, DeviceQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	index := &deviceIndex{Devices: make([]DeviceRef, 0, len(devices))}
+	for i := range devices {
+		index.Devices = append(index.Devices, *RefOf(&devices[i]))
+	}
+
+	if c.cache != nil {
+		if key, err := c.cacheKey("#device-index"); err == nil {
+			if body, err := json.Marshal(index); err == nil {
+				_ = c.cache.Put(deviceIndexScope, key, &cache.Entry{StatusCode: http.StatusOK, Body: body, StoredAt: time.Now()})
+			}
+		}
+	}
+	return index, nil
+}
+
+// RefOf returns the reference of a device
+func RefOf(d *models.Device) *DeviceRef {
+	ref := &DeviceRef{ID: d.ID, Name: d.Name}
+	if d.GroupName != nil {
+		ref.Group = *d.GroupName
+	}
+	return ref
+}
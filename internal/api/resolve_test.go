@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDeviceIndex_Resolve(t *testing.T) {
+	index := &deviceIndex{Devices: []DeviceRef{
+		{ID: "dev-1", Name: "press-01", Group: "line-1"},
+		{ID: "dev-2", Name: "press-01", Group: "line-2"},
+		{ID: "dev-3", Name: "press-02", Group: "line-1"},
+		{ID: "dev-4", Name: "lathe-01", Group: "line-1"},
+		{ID: "dev-5", Name: "lathe-01x"},
+	}}
+
+	tests := []struct {
+		ref       string
+		wantID    string
+		ambiguous int
+	}{
+		{ref: "dev-3", wantID: "dev-3"},
+		{ref: "press-02", wantID: "dev-3"},
+		{ref: "press-01@line-2", wantID: "dev-2"},
+		{ref: "press-01@LINE-1", wantID: "dev-1"},
+		{ref: "press@line-2", wantID: "dev-2"},
+		{ref: "lathe-01", wantID: "dev-4"}, // Exact name beats the longer name
+		{ref: "lat", ambiguous: 2},
+		{ref: "press-01", ambiguous: 2},
+		{ref: "press", ambiguous: 3},
+		{ref: "mill"},
+		{ref: "press-02@line-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := index.resolve(tt.ref)
+
+			var ambiguous *AmbiguousDeviceError
+			if tt.ambiguous > 0 {
+				if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != tt.ambiguous {
+					t.Fatalf("resolve(%q) error = %v, want %d candidates", tt.ref, err, tt.ambiguous)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve(%q) unexpected error: %v", tt.ref, err)
+			}
+			if tt.wantID == "" {
+				if got != nil {
+					t.Errorf("resolve(%q) = %+v, want no match", tt.ref, got)
+				}
+				return
+			}
+			if got == nil || got.ID != tt.wantID {
+				t.Errorf("resolve(%q) = %+v, want %s", tt.ref, got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestClient_ResolveDevice(t *testing.T) {
+	var lists atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/devices":
+			lists.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"items":[{"id":"dev-1","name":"press-01","groupName":"line-1"},{"id":"dev-2","name":"lathe-01"}]}`))
+		case "/api/devices/hidden-1":
+			_, _ = w.Write([]byte(`{"id":"hidden-1","name":"retired"}`))
+		default:
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	for _, ref := range []string{"press", "lathe-01", "dev-1"} {
+		if _, err := client.ResolveDevice(ctx, ref); err != nil {
+			t.Fatalf("ResolveDevice(%q) unexpected error: %v", ref, err)
+		}
+	}
+	if got := lists.Load(); got != 1 {
+		t.Errorf("device list fetched %d times, want 1 for the cached index", got)
+	}
+
+	// Devices missing from the list are looked up by ID
+	got, err := client.ResolveDevice(ctx, "hidden-1")
+	if err != nil || got.Name != "retired" {
+		t.Errorf("ResolveDevice(hidden-1) = %+v, %v; want the retired device", got, err)
+	}
+
+	if _, err := client.ResolveDevice(ctx, "mill"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolveDevice(mill) error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
+)
+
+// IdempotencyKeyHeader lets the API deduplicate replayed POST requests
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt; 0 disables retries
+	BaseDelay  time.Duration // Backoff before the first retry, doubled for each further retry
+	MaxDelay   time.Duration // Upper bound for a single backoff or Retry-After wait
+}
+
+// DefaultRetryPolicy is used when neither the config nor the profile sets one
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// RetryPolicyFromProfile returns the default retry policy with the profile's
+// overrides applied
+func RetryPolicyFromProfile(p *config.Profile) RetryPolicy {
+	policy := DefaultRetryPolicy
+	if p.Retry.MaxRetries != nil {
+		policy.MaxRetries = *p.Retry.MaxRetries
+	}
+	if p.Retry.MaxDelay > 0 {
+		policy.MaxDelay = p.Retry.MaxDelay
+	}
+	return policy
+}
+
+// RequestOption modifies an outgoing request
+type RequestOption func(*http.Request)
+
+// WithIdempotencyKey marks a POST request as safe to retry. The API uses the
+// key to recognize replays of a request it has already processed.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+}
+
+// WithHeader sets a request header. Authentication headers cannot be
+// overridden this way.
+func WithHeader(key, value string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(key, value)
+	}
+}
+
+// NewIdempotencyKey returns a random idempotency key
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// canRetry reports whether a request may be sent again. Idempotent methods
+// can; POST and PATCH only with an idempotency key. The body must be
+// replayable.
+func canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return req.Header.Get(IdempotencyKeyHeader) != ""
+	}
+}
+
+// isRetryableStatus reports whether a response status indicates a transient failure
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryableError reports whether a transport error is worth retrying.
+// Cancellation by the caller is not, nor are errors that fail every attempt
+// the same way: invalid transport settings and certificates that do not
+// verify or match a pin.
+func isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var (
+		configErr      *transport.ConfigError
+		verifyErr      *tls.CertificateVerificationError
+		unknownAuthErr x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		invalidErr     x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &configErr),
+		errors.Is(err, transport.ErrPinMismatch),
+		errors.As(err, &verifyErr),
+		errors.As(err, &unknownAuthErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return false
+	}
+	return true
+}
+
+// backoff returns the delay before the given retry (starting at 0), using
+// exponential backoff with full jitter
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << retry
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay) + 1
+}
+
+// retryAfter parses the Retry-After header as seconds or an HTTP date.
+// It returns false if the header is missing or invalid.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
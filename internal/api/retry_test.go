@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
+)
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		retryAfter   string
+		do           func(c *Client) error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:     "GET retried until success",
+			failures: 2,
+			status:   http.StatusServiceUnavailable,
+			do: func(c *Client) error {
+				_, err := c.ListDevices(context.Background())
+				return err
+			},
+			wantAttempts: 3,
+		},
+		{
+			name:     "GET gives up after max retries",
+			failures: 10,
+			status:   http.StatusBadGateway,
+			do: func(c *Client) error {
+				_, err := c.ListDevices(context.Background())
+				return err
+			},
+			wantAttempts: 4,
+			wantErr:      true,
+		},
+		{
+			name:       "429 honors Retry-After",
+			failures:   1,
+			status:     http.StatusTooManyRequests,
+			retryAfter: "0",
+			do: func(c *Client) error {
+				_, err := c.ListDevices(context.Background())
+				return err
+			},
+			wantAttempts: 2,
+		},
+		{
+			name:       "Retry-After beyond max delay is not waited for",
+			failures:   1,
+			status:     http.StatusTooManyRequests,
+			retryAfter: "3600",
+			do: func(c *Client) error {
+				_, err := c.ListDevices(context.Background())
+				return err
+			},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:     "client errors are not retried",
+			failures: 1,
+			status:   http.StatusBadRequest,
+			do: func(c *Client) error {
+				_, err := c.ListDevices(context.Background())
+				return err
+			},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:     "POST without idempotency key is not replayed",
+			failures: 1,
+			status:   http.StatusServiceUnavailable,
+			do: func(c *Client) error {
+				return c.Post(context.Background(), "/api/things", map[string]string{"a": "b"}, nil)
+			},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:     "POST with idempotency key is replayed",
+			failures: 1,
+			status:   http.StatusServiceUnavailable,
+			do: func(c *Client) error {
+				return c.Post(context.Background(), "/api/things", map[string]string{"a": "b"}, nil, WithIdempotencyKey("key-1"))
+			},
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if r.Method == http.MethodPost {
+					body := make([]byte, 64)
+					n, _ := r.Body.Read(body)
+					if string(body[:n]) != `{"a":"b"}` {
+						t.Errorf("attempt %d: body = %q, want the original body", attempts, body[:n])
+					}
+				}
+				if attempts <= tt.failures {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte(`[]`))
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server)
+
+			err := tt.do(client)
+			if tt.wantErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	rt http.RoundTripper
+	n  *int
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	*t.n++
+	return t.rt.RoundTrip(req)
+}
+
+func TestClient_RetryPermanentErrors(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	// withOptions returns a transport with the given TLS options, talking to
+	// the server as example.com, the name of its certificate
+	withOptions := func(opts transport.Options) http.RoundTripper {
+		rt, err := transport.New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rt.TLSClientConfig.ServerName = "example.com"
+		return rt
+	}
+
+	tests := []struct {
+		name         string
+		baseURL      string
+		rt           http.RoundTripper
+		wantAttempts int
+	}{
+		{
+			name:         "untrusted certificate",
+			baseURL:      server.URL,
+			rt:           http.DefaultTransport,
+			wantAttempts: 1,
+		},
+		{
+			name:         "pin mismatch",
+			baseURL:      server.URL,
+			rt:           withOptions(transport.Options{CABundle: bundle, Pins: map[string][]string{"example.com": {"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}}),
+			wantAttempts: 1,
+		},
+		{
+			name:         "invalid transport configuration",
+			baseURL:      server.URL,
+			rt:           transport.Failing(errors.New("CA bundle contains no certificates")),
+			wantAttempts: 1,
+		},
+		{
+			name:         "malformed URL",
+			baseURL:      "api.example.com",
+			rt:           http.DefaultTransport,
+			wantAttempts: 0,
+		},
+		{
+			name:         "connection refused is retried",
+			baseURL:      closed.URL,
+			rt:           http.DefaultTransport,
+			wantAttempts: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, server)
+			client.baseURL = tt.baseURL
+			attempts := 0
+			client.httpClient = &http.Client{Transport: countingTransport{rt: tt.rt, n: &attempts}}
+
+			if _, err := client.ListDevices(context.Background()); err == nil {
+				t.Fatal("ListDevices() expected error, got nil")
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for retry := 0; retry < 10; retry++ {
+		limit := min(policy.BaseDelay<<retry, policy.MaxDelay)
+		for i := 0; i < 20; i++ {
+			if d := policy.backoff(retry); d <= 0 || d > limit {
+				t.Fatalf("backoff(%d) = %v, want in (0, %v]", retry, d, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Session is a CLI session of the current user, created by 'iot auth login'
+type Session struct {
+	ID         string     `json:"id"`
+	DeviceName string     `json:"deviceName,omitempty"`
+	Platform   string     `json:"platform,omitempty"`
+	IPAddress  string     `json:"ipAddress,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	Current    bool       `json:"current,omitempty"`
+}
+
+// ListSessions retrieves the active CLI sessions of the current user
+func (c *Client) ListSessions(ctx context.Context) ([]Session, error) {
+	var sessions []Session
+	if err := c.Get(ctx, "/api/auth/cli-sessions", &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes a CLI session and the refresh token issued to it
+func (c *Client) RevokeSession(ctx context.Context, sessionID string) error {
+	return c.Delete(ctx, "/api/auth/cli-sessions/"+sessionID)
+}
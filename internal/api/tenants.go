@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+)
+
+// Tenant is a tenant the current user is a member of
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+// ListTenants retrieves the tenants the current user belongs to
+func (c *Client) ListTenants(ctx context.Context) ([]Tenant, error) {
+	var tenants []Tenant
+	if err := c.Get(ctx, "/api/tenants", &tenants); err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// WithTenant returns a copy of the client that sends requests on behalf of
+// the given tenant instead of the one selected by the profile or login
+func (c *Client) WithTenant(tenantID string) *Client {
+	clone := *c
+	clone.tenantID = tenantID
+	return &clone
+}
+
+// TenantID returns the tenant the client sends requests for
+func (c *Client) TenantID() (string, error) {
+	if c.tenantID != "" {
+		return c.tenantID, nil
+	}
+	if c.apiKey != "" {
+		return "", nil
+	}
+	return c.tokenStore.GetTenantID()
+}
@@ -1,11 +1,9 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 )
 
@@ -27,32 +25,12 @@ func (c *Client) CreateTerminalSession(ctx context.Context, deviceID string) (*T
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("unauthorized: please run 'iot auth login'")
-	}
-
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("device not found: %s", deviceID)
-	}
-
-	body, _ := io.ReadAll(resp.Body)
-
-	if resp.StatusCode != http.StatusOK {
-		// Try to parse error message
-		var errResp map[string]interface{}
-		if json.Unmarshal(body, &errResp) == nil {
-			if msg, ok := errResp["message"].(string); ok {
-				return nil, fmt.Errorf("%s", msg)
-			}
-			if msg, ok := errResp["error"].(string); ok {
-				return nil, fmt.Errorf("%s", msg)
-			}
-		}
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
 	}
 
 	var session TerminalSession
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&session); err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+)
+
+// Credential store names accepted in a profile's credential_store setting
+const (
+	CredentialStoreFile          = "file"
+	CredentialStoreEncryptedFile = "encrypted-file"
+	CredentialStoreHelper        = "helper"
+)
+
+// ErrNoCredentials is returned by a CredentialBackend that has nothing stored
+var ErrNoCredentials = errors.New("no stored credentials")
+
+// CredentialBackend persists the serialized credentials of a profile
+type CredentialBackend interface {
+	// Get returns the stored credentials or ErrNoCredentials
+	Get() ([]byte, error)
+	// Store replaces the stored credentials
+	Store(data []byte) error
+	// Erase removes the stored credentials; erasing nothing is not an error
+	Erase() error
+}
+
+// NewCredentialBackend returns the backend selected by the profile
+func NewCredentialBackend(profile *config.Profile) (CredentialBackend, error) {
+	credPath, err := profile.CredentialsPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials path: %w", err)
+	}
+
+	switch profile.CredentialStore {
+	case "", CredentialStoreFile:
+		return &fileBackend{path: credPath}, nil
+	case CredentialStoreEncryptedFile:
+		return &encryptedFileBackend{
+			path:       credPath + ".enc",
+			passphrase: Passphrase,
+		}, nil
+	case CredentialStoreHelper:
+		if strings.TrimSpace(profile.CredentialHelper) == "" {
+			return nil, fmt.Errorf("profile %q uses the helper credential store but sets no credential_helper", profile.Name)
+		}
+		host := ""
+		if u, err := url.Parse(profile.WithDefaults().APIURL); err == nil {
+			host = u.Host
+		}
+		return &helperBackend{
+			command:  profile.CredentialHelper,
+			host:     host,
+			username: profile.Name,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q (use %s, %s or %s)", profile.CredentialStore,
+			CredentialStoreFile, CredentialStoreEncryptedFile, CredentialStoreHelper)
+	}
+}
+
+// fileBackend stores credentials as plaintext JSON readable only by the owner
+type fileBackend struct {
+	path string
+}
+
+func (b *fileBackend) Get() ([]byte, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoCredentials
+		}
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	return data, nil
+}
+
+func (b *fileBackend) Store(data []byte) error {
+	return writePrivateFile(b.path, data)
+}
+
+func (b *fileBackend) Erase() error {
+	err := os.Remove(b.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+	return nil
+}
+
+// writePrivateFile writes data with owner-only permissions, creating the
+// directory if needed. The data goes to a temporary file that replaces the
+// old one, so processes reading concurrently never see a partial file.
+func writePrivateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// CreateTemp restricts permissions to the owner
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// scrypt parameters for newly encrypted files (the recommended interactive settings)
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLength   = 16
+)
+
+// PassphraseEnv is the environment variable holding the credentials passphrase
+const PassphraseEnv = "IOT_CREDENTIALS_PASSPHRASE"
+
+// encryptedEnvelope is the on-disk format of an encrypted credentials file
+type encryptedEnvelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptedFileBackend stores credentials encrypted with AES-256-GCM using
+// a key derived from a passphrase with scrypt
+type encryptedFileBackend struct {
+	path       string
+	passphrase func() ([]byte, error)
+
+	mu   sync.Mutex
+	salt []byte // Salt of the stored file, reused when storing again
+}
+
+// derivedKeys holds the AEADs of the keys derived in this process. Deriving
+// a key is slow by design and credentials are read for every request.
+var derivedKeys = struct {
+	sync.Mutex
+	m map[string]cipher.AEAD
+}{m: map[string]cipher.AEAD{}}
+
+// aead returns the AEAD for the key derived from the passphrase with the
+// given salt and parameters, deriving it once per process
+func aead(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	sum := sha256.Sum256(passphrase)
+	id := fmt.Sprintf("%x/%x/%d/%d/%d", sum, salt, n, r, p)
+
+	derivedKeys.Lock()
+	defer derivedKeys.Unlock()
+	if gcm, ok := derivedKeys.m[id]; ok {
+		return gcm, nil
+	}
+	gcm, err := newGCM(passphrase, salt, n, r, p)
+	if err != nil {
+		return nil, err
+	}
+	derivedKeys.m[id] = gcm
+	return gcm, nil
+}
+
+func (b *encryptedFileBackend) Get() ([]byte, error) {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoCredentials
+		}
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var env encryptedEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted credentials: %w", err)
+	}
+	if env.Version != 1 || env.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported encrypted credentials format (version %d, kdf %q)", env.Version, env.KDF)
+	}
+
+	passphrase, err := b.passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := aead(passphrase, env.Salt, env.N, env.R, env.P)
+	if err != nil {
+		return nil, err
+	}
+	if env.N == scryptN && env.R == scryptR && env.P == scryptP {
+		b.mu.Lock()
+		b.salt = env.Salt
+		b.mu.Unlock()
+	}
+
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt credentials: wrong passphrase or corrupted file")
+	}
+
+	return plaintext, nil
+}
+
+func (b *encryptedFileBackend) Store(data []byte) error {
+	passphrase, err := b.passphrase()
+	if err != nil {
+		return err
+	}
+
+	// The salt of the stored file is kept, so its key need not be derived
+	// again; the random nonce keeps each encryption unique
+	b.mu.Lock()
+	salt := b.salt
+	b.mu.Unlock()
+	if salt == nil {
+		salt = make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		b.mu.Lock()
+		b.salt = salt
+		b.mu.Unlock()
+	}
+
+	gcm, err := aead(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	env := encryptedEnvelope{
+		Version:    1,
+		KDF:        "scrypt",
+		N:          scryptN,
+		R:          scryptR,
+		P:          scryptP,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, data, nil),
+	}
+
+	out, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encrypted credentials: %w", err)
+	}
+
+	return writePrivateFile(b.path, out)
+}
+
+func (b *encryptedFileBackend) Erase() error {
+	err := os.Remove(b.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+	return nil
+}
+
+// deriveKey derives an AES key from a passphrase
+var deriveKey = scrypt.Key
+
+// newGCM derives the AES key from the passphrase and returns an AEAD for it
+func newGCM(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+var (
+	passphraseOnce  sync.Once
+	passphraseValue []byte
+	passphraseErr   error
+)
+
+// Passphrase returns the credentials passphrase from IOT_CREDENTIALS_PASSPHRASE,
+// or prompts for it on the terminal. The result is remembered for the
+// lifetime of the process.
+func Passphrase() ([]byte, error) {
+	passphraseOnce.Do(func() {
+		if env := os.Getenv(PassphraseEnv); env != "" {
+			passphraseValue = []byte(env)
+			return
+		}
+
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			passphraseErr = fmt.Errorf("credentials are encrypted: set %s or run interactively", PassphraseEnv)
+			return
+		}
+
+		fmt.Fprint(os.Stderr, "Credentials passphrase: ")
+		passphraseValue, passphraseErr = term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if passphraseErr == nil && len(passphraseValue) == 0 {
+			passphraseErr = errors.New("passphrase cannot be empty")
+		}
+	})
+	return passphraseValue, passphraseErr
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// helperBackend delegates storage to an external credential helper that
+// speaks the git credential helper protocol: the action (get, store or
+// erase) is passed as the last argument and key=value lines are exchanged
+// over stdin and stdout.
+//
+// Like git, a helper name without a path separator refers to the
+// executable git-credential-<name>, so existing helpers such as
+// osxkeychain, libsecret or manager can be used directly. A helper
+// starting with "!" is run as a shell command.
+type helperBackend struct {
+	command  string
+	host     string
+	username string
+}
+
+func (b *helperBackend) Get() ([]byte, error) {
+	out, err := b.run("get", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	values := parseHelperOutput(out)
+	password, ok := values["password"]
+	if !ok || password == "" {
+		return nil, ErrNoCredentials
+	}
+
+	data, err := base64.StdEncoding.DecodeString(password)
+	if err != nil {
+		return nil, fmt.Errorf("credential helper returned malformed credentials: %w", err)
+	}
+	return data, nil
+}
+
+func (b *helperBackend) Store(data []byte) error {
+	_, err := b.run("store", map[string]string{
+		"password": base64.StdEncoding.EncodeToString(data),
+	})
+	return err
+}
+
+func (b *helperBackend) Erase() error {
+	_, err := b.run("erase", nil)
+	return err
+}
+
+// run invokes the helper with the given action and returns its stdout
+func (b *helperBackend) run(action string, extra map[string]string) ([]byte, error) {
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=https\n")
+	if b.host != "" {
+		fmt.Fprintf(&input, "host=%s\n", b.host)
+	}
+	fmt.Fprintf(&input, "username=%s\n", b.username)
+	for k, v := range extra {
+		fmt.Fprintf(&input, "%s=%s\n", k, v)
+	}
+	input.WriteString("\n")
+
+	cmd := b.buildCommand(action)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %q %s failed: %w", b.command, action, err)
+	}
+	return out, nil
+}
+
+// buildCommand resolves the helper command line the way git does
+func (b *helperBackend) buildCommand(action string) *exec.Cmd {
+	if shellCmd, ok := strings.CutPrefix(b.command, "!"); ok {
+		if runtime.GOOS == "windows" {
+			return exec.Command("cmd", "/C", shellCmd+" "+action)
+		}
+		return exec.Command("sh", "-c", shellCmd+" "+action)
+	}
+
+	fields := strings.Fields(b.command)
+	name := fields[0]
+	if !filepath.IsAbs(name) && !strings.ContainsAny(name, `/\`) {
+		name = "git-credential-" + name
+	}
+
+	args := append(fields[1:], action)
+	return exec.Command(name, args...)
+}
+
+// parseHelperOutput parses the key=value lines written by a credential helper
+func parseHelperOutput(out []byte) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			values[k] = v
+		}
+	}
+	return values
+}
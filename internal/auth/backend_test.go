@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestEncryptedFileBackend_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json.enc")
+	backend := &encryptedFileBackend{
+		path:       path,
+		passphrase: func() ([]byte, error) { return []byte("correct horse"), nil },
+	}
+
+	if _, err := backend.Get(); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("Get() on empty store error = %v, want ErrNoCredentials", err)
+	}
+
+	secret := []byte(`{"refreshToken":"secret-refresh-token"}`)
+	if err := backend.Store(secret); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("secret-refresh-token")) {
+		t.Error("Store() wrote the plaintext token to disk")
+	}
+
+	got, err := backend.Get()
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Errorf("Get() = %s, want %s", got, secret)
+	}
+
+	wrong := &encryptedFileBackend{
+		path:       path,
+		passphrase: func() ([]byte, error) { return []byte("wrong"), nil },
+	}
+	if _, err := wrong.Get(); err == nil {
+		t.Error("Get() with wrong passphrase expected error, got nil")
+	}
+
+	if err := backend.Erase(); err != nil {
+		t.Fatalf("Erase() unexpected error: %v", err)
+	}
+	if _, err := backend.Get(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Get() after Erase() error = %v, want ErrNoCredentials", err)
+	}
+}
+
+func TestEncryptedFileBackend_DerivesKeyOnce(t *testing.T) {
+	derivations := 0
+	deriveKey = func(password, salt []byte, n, r, p, keyLen int) ([]byte, error) {
+		derivations++
+		return scrypt.Key(password, salt, n, r, p, keyLen)
+	}
+	t.Cleanup(func() { deriveKey = scrypt.Key })
+
+	path := filepath.Join(t.TempDir(), "credentials.json.enc")
+	passphrase := func() ([]byte, error) { return []byte("correct horse"), nil }
+	backend := &encryptedFileBackend{path: path, passphrase: passphrase}
+
+	for i := 0; i < 5; i++ {
+		if err := backend.Store([]byte(`{"accessToken":"a"}`)); err != nil {
+			t.Fatalf("Store() unexpected error: %v", err)
+		}
+		if _, err := backend.Get(); err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+	}
+	if derivations != 1 {
+		t.Errorf("derived the key %d times, want 1", derivations)
+	}
+
+	// Other backends of the process reuse the key
+	other := &encryptedFileBackend{path: path, passphrase: passphrase}
+	if _, err := other.Get(); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if _, err := other.Get(); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if err := other.Store([]byte(`{"accessToken":"b"}`)); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+	if derivations != 1 {
+		t.Errorf("derived the key %d times in all backends, want 1", derivations)
+	}
+}
+
+func TestHelperBackend_RoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	store := filepath.Join(dir, "store")
+
+	// A minimal helper that keeps the password line of the last store in a file
+	script := `#!/bin/sh
+case "$1" in
+  get)   if [ -f "` + store + `" ]; then cat "` + store + `"; fi ;;
+  store) grep '^password=' > "` + store + `" ;;
+  erase) rm -f "` + store + `" ;;
+esac
+`
+	helper := filepath.Join(dir, "helper")
+	if err := os.WriteFile(helper, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &helperBackend{command: helper, host: "api.example.com", username: "default"}
+
+	if _, err := backend.Get(); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("Get() on empty store error = %v, want ErrNoCredentials", err)
+	}
+
+	secret := []byte(`{"accessToken":"a","refreshToken":"r"}`)
+	if err := backend.Store(secret); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	got, err := backend.Get()
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Errorf("Get() = %s, want %s", got, secret)
+	}
+
+	if err := backend.Erase(); err != nil {
+		t.Fatalf("Erase() unexpected error: %v", err)
+	}
+	if _, err := backend.Get(); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Get() after Erase() error = %v, want ErrNoCredentials", err)
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
+)
+
+// ClockSkew estimates how far the server clock at url is ahead of the local
+// clock, based on the Date header of a HEAD request. The Date header has a
+// resolution of one second, so the estimate is only accurate to about that.
+func ClockSkew(ctx context.Context, url string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := transport.NewClient(0).Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+	end := time.Now()
+
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return 0, fmt.Errorf("server sent no Date header")
+	}
+
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header: %w", err)
+	}
+
+	// Compare against the midpoint of the round trip
+	local := start.Add(end.Sub(start) / 2)
+	return serverTime.Sub(local).Round(time.Second), nil
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-90*time.Second).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	skew, err := ClockSkew(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("ClockSkew() unexpected error: %v", err)
+	}
+
+	if skew < -92*time.Second || skew > -88*time.Second {
+		t.Errorf("ClockSkew() = %v, want about -90s", skew)
+	}
+}
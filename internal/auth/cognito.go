@@ -3,7 +3,10 @@ package auth
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
@@ -15,14 +18,28 @@ type CognitoConfig struct {
 	Region     string
 	UserPoolID string
 	ClientID   string
+	Endpoint   string // Overrides the regional Cognito endpoint, e.g. for a local fake
+	Issuer     string // Overrides the token issuer derived from region and user pool
 }
 
 // DefaultCognitoConfig returns the default Cognito configuration
 func DefaultCognitoConfig() CognitoConfig {
 	return CognitoConfig{
-		Region:     "eu-central-1",
-		UserPoolID: "eu-central-1_SyQyrM9xc",
-		ClientID:   "70sngp1h120tni8csqp3s683an",
+		Region:     config.Defaults.CognitoRegion,
+		UserPoolID: config.Defaults.UserPoolID,
+		ClientID:   config.Defaults.ClientID,
+	}
+}
+
+// CognitoConfigFromProfile returns the Cognito configuration of a profile
+func CognitoConfigFromProfile(p *config.Profile) CognitoConfig {
+	p = p.WithDefaults()
+	return CognitoConfig{
+		Region:     p.Cognito.Region,
+		UserPoolID: p.Cognito.UserPoolID,
+		ClientID:   p.Cognito.ClientID,
+		Endpoint:   p.Cognito.Endpoint,
+		Issuer:     p.Cognito.Issuer,
 	}
 }
 
@@ -34,6 +51,33 @@ type AuthResult struct {
 	ExpiresIn    int32
 }
 
+// Cognito challenges that can be answered interactively
+const (
+	ChallengeSoftwareTokenMFA = "SOFTWARE_TOKEN_MFA"
+	ChallengeSMSMFA           = "SMS_MFA"
+	ChallengeNewPassword      = "NEW_PASSWORD_REQUIRED"
+)
+
+// maxChallenges bounds the number of challenge round trips in one login
+const maxChallenges = 5
+
+// Challenge describes an additional step Cognito requires to complete a login
+type Challenge struct {
+	Name        string
+	Destination string // Where an SMS code was sent, if applicable
+}
+
+// ChallengeHandler answers a challenge: the MFA code for SOFTWARE_TOKEN_MFA
+// and SMS_MFA, or the new password for NEW_PASSWORD_REQUIRED
+type ChallengeHandler func(ctx context.Context, challenge Challenge) (string, error)
+
+// challengeResponseKeys maps each supported challenge to the response key carrying the answer
+var challengeResponseKeys = map[string]string{
+	ChallengeSoftwareTokenMFA: "SOFTWARE_TOKEN_MFA_CODE",
+	ChallengeSMSMFA:           "SMS_MFA_CODE",
+	ChallengeNewPassword:      "NEW_PASSWORD",
+}
+
 // CognitoClient wraps the Cognito Identity Provider client
 type CognitoClient struct {
 	client *cognitoidentityprovider.Client
@@ -42,14 +86,22 @@ type CognitoClient struct {
 
 // NewCognitoClient creates a new Cognito client
 func NewCognitoClient(cfg CognitoConfig) (*CognitoClient, error) {
+	// The user pool APIs used here are authorized by the tokens themselves,
+	// so requests are never signed with the user's AWS credentials
 	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(),
 		awsconfig.WithRegion(cfg.Region),
+		awsconfig.WithCredentialsProvider(aws.AnonymousCredentials{}),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	client := cognitoidentityprovider.NewFromConfig(awsCfg)
+	client := cognitoidentityprovider.NewFromConfig(awsCfg, func(o *cognitoidentityprovider.Options) {
+		o.HTTPClient = transport.NewClient(30 * time.Second)
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
 
 	return &CognitoClient{
 		client: client,
@@ -57,8 +109,9 @@ func NewCognitoClient(cfg CognitoConfig) (*CognitoClient, error) {
 	}, nil
 }
 
-// Authenticate performs SRP authentication with email and password
-func (c *CognitoClient) Authenticate(ctx context.Context, email, password string) (*AuthResult, error) {
+// Authenticate performs password authentication with email and password.
+// Challenges such as MFA are answered through handler.
+func (c *CognitoClient) Authenticate(ctx context.Context, email, password string, handler ChallengeHandler) (*AuthResult, error) {
 	input := &cognitoidentityprovider.InitiateAuthInput{
 		AuthFlow: types.AuthFlowTypeUserPasswordAuth,
 		ClientId: aws.String(c.config.ClientID),
@@ -73,19 +126,63 @@ func (c *CognitoClient) Authenticate(ctx context.Context, email, password string
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
-	if result.ChallengeName != "" {
-		return nil, fmt.Errorf("authentication requires additional challenge: %s", result.ChallengeName)
+	challengeName := result.ChallengeName
+	session := result.Session
+	params := result.ChallengeParameters
+	authResult := result.AuthenticationResult
+
+	for i := 0; challengeName != ""; i++ {
+		if i == maxChallenges {
+			return nil, fmt.Errorf("authentication failed: too many challenges")
+		}
+
+		responseKey, ok := challengeResponseKeys[string(challengeName)]
+		if !ok || handler == nil {
+			return nil, fmt.Errorf("authentication requires additional challenge: %s", challengeName)
+		}
+
+		answer, err := handler(ctx, Challenge{
+			Name:        string(challengeName),
+			Destination: params["CODE_DELIVERY_DESTINATION"],
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Cognito identifies the user by its internal name once a challenge is issued
+		username := email
+		if id := params["USER_ID_FOR_SRP"]; id != "" {
+			username = id
+		}
+
+		resp, err := c.client.RespondToAuthChallenge(ctx, &cognitoidentityprovider.RespondToAuthChallengeInput{
+			ClientId:      aws.String(c.config.ClientID),
+			ChallengeName: challengeName,
+			Session:       session,
+			ChallengeResponses: map[string]string{
+				"USERNAME":  username,
+				responseKey: answer,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("challenge %s failed: %w", challengeName, err)
+		}
+
+		challengeName = resp.ChallengeName
+		session = resp.Session
+		params = resp.ChallengeParameters
+		authResult = resp.AuthenticationResult
 	}
 
-	if result.AuthenticationResult == nil {
+	if authResult == nil {
 		return nil, fmt.Errorf("authentication failed: no result returned")
 	}
 
 	return &AuthResult{
-		AccessToken:  aws.ToString(result.AuthenticationResult.AccessToken),
-		IDToken:      aws.ToString(result.AuthenticationResult.IdToken),
-		RefreshToken: aws.ToString(result.AuthenticationResult.RefreshToken),
-		ExpiresIn:    result.AuthenticationResult.ExpiresIn,
+		AccessToken:  aws.ToString(authResult.AccessToken),
+		IDToken:      aws.ToString(authResult.IdToken),
+		RefreshToken: aws.ToString(authResult.RefreshToken),
+		ExpiresIn:    authResult.ExpiresIn,
 	}, nil
 }
 
@@ -108,10 +205,40 @@ func (c *CognitoClient) RefreshTokens(ctx context.Context, refreshToken string)
 		return nil, fmt.Errorf("token refresh failed: no result returned")
 	}
 
+	// Cognito only returns a new refresh token when rotation is enabled
+	newRefreshToken := aws.ToString(result.AuthenticationResult.RefreshToken)
+	if newRefreshToken == "" {
+		newRefreshToken = refreshToken
+	}
+
 	return &AuthResult{
 		AccessToken:  aws.ToString(result.AuthenticationResult.AccessToken),
 		IDToken:      aws.ToString(result.AuthenticationResult.IdToken),
-		RefreshToken: refreshToken, // Cognito doesn't always return a new refresh token
+		RefreshToken: newRefreshToken,
 		ExpiresIn:    result.AuthenticationResult.ExpiresIn,
 	}, nil
 }
+
+// RevokeToken revokes a refresh token and the access and ID tokens issued with it
+func (c *CognitoClient) RevokeToken(ctx context.Context, refreshToken string) error {
+	_, err := c.client.RevokeToken(ctx, &cognitoidentityprovider.RevokeTokenInput{
+		ClientId: aws.String(c.config.ClientID),
+		Token:    aws.String(refreshToken),
+	})
+	if err != nil {
+		return fmt.Errorf("token revocation failed: %w", err)
+	}
+	return nil
+}
+
+// GlobalSignOut invalidates all refresh tokens of the user, signing them out
+// on every device
+func (c *CognitoClient) GlobalSignOut(ctx context.Context, accessToken string) error {
+	_, err := c.client.GlobalSignOut(ctx, &cognitoidentityprovider.GlobalSignOutInput{
+		AccessToken: aws.String(accessToken),
+	})
+	if err != nil {
+		return fmt.Errorf("global sign-out failed: %w", err)
+	}
+	return nil
+}
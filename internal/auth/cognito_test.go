@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeCognito implements the InitiateAuth, RespondToAuthChallenge and
+// RevokeToken calls of the Cognito user pool API, requiring a TOTP code
+// after the password
+func fakeCognito(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		target := r.Header.Get("X-Amz-Target")
+
+		switch {
+		case strings.HasSuffix(target, ".InitiateAuth"):
+			params, _ := body["AuthParameters"].(map[string]interface{})
+			if params["PASSWORD"] != "secret" {
+				w.Header().Set("X-Amzn-ErrorType", "NotAuthorizedException")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"NotAuthorizedException","message":"Incorrect username or password."}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ChallengeName":"SOFTWARE_TOKEN_MFA","Session":"session-1","ChallengeParameters":{"USER_ID_FOR_SRP":"user-123"}}`))
+
+		case strings.HasSuffix(target, ".RespondToAuthChallenge"):
+			responses, _ := body["ChallengeResponses"].(map[string]interface{})
+			if body["Session"] != "session-1" || responses["USERNAME"] != "user-123" || responses["SOFTWARE_TOKEN_MFA_CODE"] != "123456" {
+				w.Header().Set("X-Amzn-ErrorType", "CodeMismatchException")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"CodeMismatchException","message":"Invalid code."}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"AuthenticationResult":{"AccessToken":"access","IdToken":"id","RefreshToken":"refresh","ExpiresIn":3600}}`))
+
+		case strings.HasSuffix(target, ".RevokeToken"):
+			if body["Token"] != "refresh" || body["ClientId"] != "client" {
+				w.Header().Set("X-Amzn-ErrorType", "UnauthorizedException")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"UnauthorizedException","message":"Invalid token."}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+
+		default:
+			t.Errorf("unexpected target %q", target)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestCognitoClient_AuthenticateWithMFA(t *testing.T) {
+	server := fakeCognito(t)
+	defer server.Close()
+
+	client, err := NewCognitoClient(CognitoConfig{
+		Region:   "eu-central-1",
+		ClientID: "client",
+		Endpoint: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewCognitoClient() unexpected error: %v", err)
+	}
+
+	var asked []string
+	handler := func(ctx context.Context, ch Challenge) (string, error) {
+		asked = append(asked, ch.Name)
+		return "123456", nil
+	}
+
+	result, err := client.Authenticate(context.Background(), "user@example.com", "secret", handler)
+	if err != nil {
+		t.Fatalf("Authenticate() unexpected error: %v", err)
+	}
+
+	if len(asked) != 1 || asked[0] != ChallengeSoftwareTokenMFA {
+		t.Errorf("challenges asked = %v, want [%s]", asked, ChallengeSoftwareTokenMFA)
+	}
+	if result.AccessToken != "access" || result.RefreshToken != "refresh" || result.ExpiresIn != 3600 {
+		t.Errorf("Authenticate() = %+v, want tokens from the fake", result)
+	}
+
+	if _, err := client.Authenticate(context.Background(), "user@example.com", "wrong", handler); err == nil {
+		t.Error("Authenticate() with wrong password expected error, got nil")
+	}
+}
+
+func TestCognitoClient_RevokeToken(t *testing.T) {
+	server := fakeCognito(t)
+	defer server.Close()
+
+	client, err := NewCognitoClient(CognitoConfig{
+		Region:   "eu-central-1",
+		ClientID: "client",
+		Endpoint: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewCognitoClient() unexpected error: %v", err)
+	}
+
+	if err := client.RevokeToken(context.Background(), "refresh"); err != nil {
+		t.Errorf("RevokeToken() unexpected error: %v", err)
+	}
+	if err := client.RevokeToken(context.Background(), "unknown"); err == nil {
+		t.Error("RevokeToken() with unknown token expected error, got nil")
+	}
+}
@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
+)
+
+const (
+	// clockLeeway tolerates small clock differences when checking iat and exp
+	clockLeeway = time.Minute
+	// jwksMaxAge is how long cached signing keys are used before refetching
+	jwksMaxAge = 24 * time.Hour
+	// jwksCacheFile is the name of the signing key cache in the profile directory
+	jwksCacheFile = "jwks.json"
+)
+
+// Token uses as set in the token_use claim
+const (
+	TokenUseID     = "id"
+	TokenUseAccess = "access"
+)
+
+// Claims are the verified claims of a Cognito ID or access token
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  string   `json:"aud,omitempty"`       // ID tokens
+	ClientID  string   `json:"client_id,omitempty"` // Access tokens
+	TokenUse  string   `json:"token_use"`
+	Email     string   `json:"email,omitempty"`
+	Username  string   `json:"cognito:username,omitempty"`
+	Groups    []string `json:"cognito:groups,omitempty"`
+	TenantID  string   `json:"custom:tenant_id,omitempty"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	AuthTime  int64    `json:"auth_time,omitempty"`
+}
+
+// Expiry returns the exp claim as a time
+func (c *Claims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// IssuedAtTime returns the iat claim as a time
+func (c *Claims) IssuedAtTime() time.Time {
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// jwk is a JSON Web Key as published in the user pool's JWKS
+type jwk struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// jwksCache is the on-disk cache of the signing keys
+type jwksCache struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	Keys      []jwk     `json:"keys"`
+}
+
+// Verifier checks the signature, issuer, audience and lifetime of tokens
+// issued by a Cognito user pool. Signing keys are fetched from the pool's
+// JWKS and cached on disk; an unknown key ID triggers a refetch so that
+// key rotation is picked up.
+type Verifier struct {
+	issuer     string
+	clientID   string
+	jwksURL    string
+	cachePath  string
+	httpClient *http.Client
+	now        func() time.Time
+
+	keys    map[string]*rsa.PublicKey
+	fetched bool // keys were fetched by this process
+}
+
+// NewVerifier creates a verifier for the user pool in cfg, caching keys in cacheDir
+func NewVerifier(cfg CognitoConfig, cacheDir string) *Verifier {
+	issuer := cfg.Issuer
+	if issuer == "" {
+		issuer = fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", cfg.Region, cfg.UserPoolID)
+	}
+
+	return &Verifier{
+		issuer:     issuer,
+		clientID:   cfg.ClientID,
+		jwksURL:    strings.TrimSuffix(issuer, "/") + "/.well-known/jwks.json",
+		cachePath:  filepath.Join(cacheDir, jwksCacheFile),
+		httpClient: transport.NewClient(10 * time.Second),
+		now:        time.Now,
+	}
+}
+
+// Verify checks token and returns its claims. use is the expected
+// token_use claim (TokenUseID or TokenUseAccess).
+func (v *Verifier) Verify(ctx context.Context, token, use string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid JWT format")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWT header: %w", err)
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse JWT header: %w", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", header.Algorithm)
+	}
+
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWT signature: %w", err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid JWT signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWT payload: %w", err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse JWT claims: %w", err)
+	}
+
+	if err := v.checkClaims(&claims, use); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// checkClaims validates the registered claims of a token with a good signature
+func (v *Verifier) checkClaims(claims *Claims, use string) error {
+	if claims.Issuer != v.issuer {
+		return fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	}
+
+	if claims.TokenUse != use {
+		return fmt.Errorf("unexpected token use %q, want %q", claims.TokenUse, use)
+	}
+
+	audience := claims.Audience
+	if use == TokenUseAccess {
+		audience = claims.ClientID
+	}
+	if audience != v.clientID {
+		return fmt.Errorf("token was issued for client %q, not %q", audience, v.clientID)
+	}
+
+	now := v.now()
+	if claims.ExpiresAt == 0 || now.After(claims.Expiry().Add(clockLeeway)) {
+		return fmt.Errorf("token expired at %s", claims.Expiry().Format(time.RFC3339))
+	}
+	if claims.IssuedAtTime().After(now.Add(clockLeeway)) {
+		return fmt.Errorf("token issued in the future (check your system clock)")
+	}
+
+	return nil
+}
+
+// key returns the signing key with the given ID, refreshing the key set if needed
+func (v *Verifier) key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	if v.keys == nil {
+		if cache, err := v.loadCache(); err == nil && v.now().Sub(cache.FetchedAt) < jwksMaxAge {
+			v.keys = parseJWKs(cache.Keys)
+		}
+	}
+
+	if key, ok := v.keys[keyID]; ok {
+		return key, nil
+	}
+
+	// Unknown key: the pool may have rotated its keys since they were cached
+	if !v.fetched {
+		if err := v.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := v.keys[keyID]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown JWT signing key %q", keyID)
+}
+
+// fetchKeys downloads the key set and updates the cache
+func (v *Verifier) fetchKeys(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", v.jwksURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch signing keys: server returned %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode signing keys: %w", err)
+	}
+
+	v.keys = parseJWKs(set.Keys)
+	v.fetched = true
+
+	// Caching is best effort; verification works without it
+	_ = v.saveCache(&jwksCache{FetchedAt: v.now(), Keys: set.Keys})
+
+	return nil
+}
+
+func (v *Verifier) loadCache() (*jwksCache, error) {
+	data, err := os.ReadFile(v.cachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache jwksCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+func (v *Verifier) saveCache(cache *jwksCache) error {
+	if err := os.MkdirAll(filepath.Dir(v.cachePath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(v.cachePath, data, 0600)
+}
+
+// parseJWKs converts the RSA signing keys of a key set, skipping anything else
+func parseJWKs(keys []jwk) map[string]*rsa.PublicKey {
+	result := make(map[string]*rsa.PublicKey)
+	for _, k := range keys {
+		if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := rsaPublicKey(k)
+		if err != nil {
+			continue
+		}
+		result[k.KeyID] = key
+	}
+	return result
+}
+
+// rsaPublicKey decodes the modulus and exponent of an RSA JWK
+func rsaPublicKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("RSA exponent too large")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
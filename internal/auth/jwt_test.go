@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testClientID = "test-client"
+
+// testIssuer is a fake user pool that serves its JWKS and signs tokens
+type testIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	keyID  string
+	hits   int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testIssuer{key: key, keyID: "key-1"}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer.hits++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": issuer.keyID,
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(issuer.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(issuer.key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+// config returns a Cognito configuration pointing at the fake pool
+func (i *testIssuer) config() CognitoConfig {
+	return CognitoConfig{ClientID: testClientID, Issuer: i.server.URL}
+}
+
+// token signs a token of the given use that expires after ttl
+func (i *testIssuer) token(t *testing.T, use string, ttl time.Duration, extra map[string]interface{}) string {
+	t.Helper()
+
+	claims := map[string]interface{}{
+		"sub":       "user-123",
+		"iss":       i.server.URL,
+		"token_use": use,
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(ttl).Unix(),
+	}
+	if use == TokenUseID {
+		claims["aud"] = testClientID
+	} else {
+		claims["client_id"] = testClientID
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": i.keyID})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	issuer := newTestIssuer(t)
+	other := newTestIssuer(t)
+
+	tests := []struct {
+		name    string
+		token   string
+		use     string
+		wantErr bool
+	}{
+		{
+			name:  "valid ID token",
+			token: issuer.token(t, TokenUseID, time.Hour, map[string]interface{}{"custom:tenant_id": "tenant-1"}),
+			use:   TokenUseID,
+		},
+		{
+			name:  "valid access token",
+			token: issuer.token(t, TokenUseAccess, time.Hour, nil),
+			use:   TokenUseAccess,
+		},
+		{
+			name:    "expired",
+			token:   issuer.token(t, TokenUseID, -time.Hour, nil),
+			use:     TokenUseID,
+			wantErr: true,
+		},
+		{
+			name:    "wrong token use",
+			token:   issuer.token(t, TokenUseAccess, time.Hour, nil),
+			use:     TokenUseID,
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   issuer.token(t, TokenUseID, time.Hour, map[string]interface{}{"aud": "other-client"}),
+			use:     TokenUseID,
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			token:   issuer.token(t, TokenUseID, time.Hour, map[string]interface{}{"iss": "https://evil.example.com"}),
+			use:     TokenUseID,
+			wantErr: true,
+		},
+		{
+			name:    "signed by another key",
+			token:   other.token(t, TokenUseID, time.Hour, map[string]interface{}{"iss": issuer.server.URL}),
+			use:     TokenUseID,
+			wantErr: true,
+		},
+		{
+			name: "tampered payload",
+			token: func() string {
+				parts := strings.Split(issuer.token(t, TokenUseID, time.Hour, nil), ".")
+				payload, _ := json.Marshal(map[string]interface{}{"custom:tenant_id": "someone-else"})
+				parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+				return strings.Join(parts, ".")
+			}(),
+			use:     TokenUseID,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(issuer.config(), t.TempDir())
+			_, err := verifier.Verify(context.Background(), tt.token, tt.use)
+			if tt.wantErr && err == nil {
+				t.Error("Verify() expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Verify() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifier_KeyCacheAndRotation(t *testing.T) {
+	issuer := newTestIssuer(t)
+	cacheDir := t.TempDir()
+
+	token := issuer.token(t, TokenUseID, time.Hour, nil)
+	if _, err := NewVerifier(issuer.config(), cacheDir).Verify(context.Background(), token, TokenUseID); err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+
+	// A new verifier uses the cached keys
+	if _, err := NewVerifier(issuer.config(), cacheDir).Verify(context.Background(), token, TokenUseID); err != nil {
+		t.Fatalf("Verify() with cached keys unexpected error: %v", err)
+	}
+	if issuer.hits != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", issuer.hits)
+	}
+
+	// After rotation the unknown key ID triggers a refetch
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.key = rotated
+	issuer.keyID = "key-2"
+
+	token = issuer.token(t, TokenUseID, time.Hour, nil)
+	if _, err := NewVerifier(issuer.config(), cacheDir).Verify(context.Background(), token, TokenUseID); err != nil {
+		t.Fatalf("Verify() after rotation unexpected error: %v", err)
+	}
+	if issuer.hits != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", issuer.hits)
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// lockTimeout bounds how long we wait for another process to release the lock
+	lockTimeout = 30 * time.Second
+	// lockRetryInterval is how often we retry a contended lock
+	lockRetryInterval = 50 * time.Millisecond
+)
+
+// errLockBusy is returned by tryLock when another process holds the lock
+var errLockBusy = errors.New("lock is held by another process")
+
+// fileLock is an exclusive, cross-process advisory lock backed by a file
+type fileLock struct {
+	file *os.File
+}
+
+// acquireLock takes an exclusive lock on path, waiting until the lock
+// becomes available, the context is cancelled or lockTimeout elapses
+func acquireLock(ctx context.Context, path string) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := tryLock(f)
+		if err == nil {
+			return &fileLock{file: f}, nil
+		}
+		if !errors.Is(err, errLockBusy) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out waiting for lock on %s", path)
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Unlock releases the lock
+func (l *fileLock) Unlock() {
+	_ = unlock(l.file)
+	l.file.Close()
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package auth
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock attempts to take an exclusive flock without blocking
+func tryLock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockBusy
+	}
+	return err
+}
+
+// unlock releases a lock taken with tryLock
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
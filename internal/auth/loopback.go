@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
+)
+
+// loopbackScopes are the OAuth2 scopes requested from the hosted UI
+const loopbackScopes = "openid email profile"
+
+// LoopbackAuth handles the OAuth2 authorization code flow with PKCE against
+// the Cognito hosted UI, receiving the code on a loopback redirect
+type LoopbackAuth struct {
+	authURL    string
+	clientID   string
+	port       int
+	httpClient *http.Client
+}
+
+// NewLoopbackAuth creates a new LoopbackAuth instance for the given profile.
+// A port of 0 listens on a random free port.
+func NewLoopbackAuth(profile *config.Profile, port int) *LoopbackAuth {
+	profile = profile.WithDefaults()
+	return &LoopbackAuth{
+		authURL:    strings.TrimRight(profile.AuthURL, "/"),
+		clientID:   profile.Cognito.ClientID,
+		port:       port,
+		httpClient: transport.NewClient(30 * time.Second),
+	}
+}
+
+// callbackResult is what the redirect delivers to the waiting login
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// tokenResponse is the response of the hosted UI's token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int32  `json:"expires_in"`
+	Error        string `json:"error"`
+}
+
+// Login listens on 127.0.0.1, calls open with the authorization URL and
+// waits for the browser to be redirected back with the authorization code,
+// which is then exchanged for tokens
+func (l *LoopbackAuth) Login(ctx context.Context, open func(authorizeURL string) error) (*AuthResult, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", l.port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start loopback listener: %w", err)
+	}
+	defer listener.Close()
+
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan callbackResult, 1)
+	server := &http.Server{
+		Handler:           l.callbackHandler(state, results),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	if err := open(l.authorizeURL(redirectURI, state, pkceChallenge(verifier))); err != nil {
+		return nil, err
+	}
+
+	var result callbackResult
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("authentication timed out: %w", ctx.Err())
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	return l.exchangeCode(ctx, result.code, redirectURI, verifier)
+}
+
+// authorizeURL builds the hosted UI authorization URL
+func (l *LoopbackAuth) authorizeURL(redirectURI, state, challenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {l.clientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {loopbackScopes},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	return l.authURL + "/oauth2/authorize?" + query.Encode()
+}
+
+// callbackHandler handles the redirect from the hosted UI. Only the first
+// request carrying the expected state is delivered.
+func (l *LoopbackAuth) callbackHandler(state string, results chan<- callbackResult) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Invalid state. Please start the login again.", http.StatusBadRequest)
+			return
+		}
+
+		var result callbackResult
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization failed: %s %s", query.Get("error"), query.Get("error_description"))
+			http.Error(w, "Authentication failed. You can close this window.", http.StatusBadRequest)
+		case query.Get("code") == "":
+			result.err = errors.New("authorization failed: no code returned")
+			http.Error(w, "Authentication failed. You can close this window.", http.StatusBadRequest)
+		default:
+			result.code = query.Get("code")
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><body><h3>Authentication complete.</h3><p>You can close this window and return to the terminal.</p></body></html>")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	return mux
+}
+
+// exchangeCode exchanges the authorization code for tokens
+func (l *LoopbackAuth) exchangeCode(ctx context.Context, code, redirectURI, verifier string) (*AuthResult, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {l.clientID},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", l.authURL+"/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token exchange failed: server returned %d %s", resp.StatusCode, tokens.Error)
+	}
+
+	return &AuthResult{
+		AccessToken:  tokens.AccessToken,
+		IDToken:      tokens.IDToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// pkceChallenge derives the S256 code challenge from a code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded as unpadded base64url
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
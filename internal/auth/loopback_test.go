@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+)
+
+func TestLoopbackAuth_Login(t *testing.T) {
+	var challenge string
+	hostedUI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/token" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Form.Get("code") != "auth-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		if pkceChallenge(r.Form.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access",
+			"id_token":      "id",
+			"refresh_token": "refresh",
+			"expires_in":    3600,
+		})
+	}))
+	defer hostedUI.Close()
+
+	tests := []struct {
+		name     string
+		redirect func(authorize *url.URL) url.Values
+		wantErr  bool
+	}{
+		{
+			name: "code returned",
+			redirect: func(authorize *url.URL) url.Values {
+				return url.Values{"code": {"auth-code"}, "state": {authorize.Query().Get("state")}}
+			},
+		},
+		{
+			name: "access denied",
+			redirect: func(authorize *url.URL) url.Values {
+				return url.Values{"error": {"access_denied"}, "state": {authorize.Query().Get("state")}}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loopback := NewLoopbackAuth(&config.Profile{AuthURL: hostedUI.URL}, 0)
+
+			// The browser: follow the authorization URL straight to the redirect
+			open := func(authorizeURL string) error {
+				authorize, err := url.Parse(authorizeURL)
+				if err != nil {
+					return err
+				}
+				challenge = authorize.Query().Get("code_challenge")
+
+				// A request with a wrong state must be ignored
+				resp, err := http.Get(authorize.Query().Get("redirect_uri") + "?code=stolen&state=wrong")
+				if err != nil {
+					return err
+				}
+				resp.Body.Close()
+
+				go func() {
+					resp, err := http.Get(authorize.Query().Get("redirect_uri") + "?" + tt.redirect(authorize).Encode())
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			result, err := loopback.Login(ctx, open)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Login() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Login() unexpected error: %v", err)
+			}
+			if result.AccessToken != "access" || result.IDToken != "id" || result.RefreshToken != "refresh" {
+				t.Errorf("Login() = %+v, want the exchanged tokens", result)
+			}
+		})
+	}
+}
@@ -1,20 +1,46 @@
 package auth
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"runtime"
 	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
 )
 
 // CLISession represents a pending CLI authentication session
 type CLISession struct {
 	SessionID string `json:"sessionId"`
 	LoginURL  string `json:"loginUrl"`
+	UserCode  string `json:"userCode,omitempty"` // short code shown on the login page for confirmation
 	ExpiresAt string `json:"expiresAt"`
 }
 
+// PrintInstructions writes how to complete the session on another device
+func (s *CLISession) PrintInstructions(w io.Writer) {
+	fmt.Fprintln(w, "To complete authentication, open this URL on any device:")
+	fmt.Fprintf(w, "  %s\n", s.LoginURL)
+	if s.UserCode != "" {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Confirm that the page shows this code: %s\n", s.UserCode)
+	}
+	fmt.Fprintln(w)
+}
+
+// createSessionRequest identifies the machine a CLI session is created on,
+// so that it can be recognized in 'iot auth sessions'
+type createSessionRequest struct {
+	DeviceName string `json:"deviceName,omitempty"`
+	Platform   string `json:"platform"`
+}
+
 // CLISessionStatus represents the status of a CLI session
 type CLISessionStatus struct {
 	Status       string `json:"status"` // "pending", "completed", "expired"
@@ -30,19 +56,26 @@ type SessionAuth struct {
 	httpClient *http.Client
 }
 
-// NewSessionAuth creates a new SessionAuth instance
-func NewSessionAuth(apiURL string) *SessionAuth {
+// NewSessionAuth creates a new SessionAuth instance for the given profile
+func NewSessionAuth(profile *config.Profile) *SessionAuth {
 	return &SessionAuth{
-		apiURL: apiURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiURL:     profile.WithDefaults().APIURL,
+		httpClient: transport.NewClient(30 * time.Second),
 	}
 }
 
 // CreateSession creates a new CLI authentication session
 func (s *SessionAuth) CreateSession(ctx context.Context) (*CLISession, error) {
-	req, err := http.NewRequestWithContext(ctx, "POST", s.apiURL+"/api/auth/cli-session", nil)
+	hostname, _ := os.Hostname()
+	body, err := json.Marshal(createSessionRequest{
+		DeviceName: hostname,
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiURL+"/api/auth/cli-session", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
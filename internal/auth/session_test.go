@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCLISession_PrintInstructions(t *testing.T) {
+	tests := []struct {
+		name     string
+		session  CLISession
+		wantCode bool
+	}{
+		{
+			name:     "code from the server",
+			session:  CLISession{SessionID: "sess-1", LoginURL: "https://app.example.com/cli/sess-1", UserCode: "WXYZ-1234"},
+			wantCode: true,
+		},
+		{
+			name:    "no code",
+			session: CLISession{SessionID: "sess-1", LoginURL: "https://app.example.com/cli/sess-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tt.session.PrintInstructions(&out)
+
+			if !strings.Contains(out.String(), "  "+tt.session.LoginURL+"\n") {
+				t.Errorf("instructions do not show the login URL:\n%s", out.String())
+			}
+			showsCode := strings.Contains(out.String(), "shows this code")
+			if tt.wantCode && !strings.Contains(out.String(), "shows this code: "+tt.session.UserCode+"\n") {
+				t.Errorf("instructions do not show code %s:\n%s", tt.session.UserCode, out.String())
+			}
+			if !tt.wantCode && showsCode {
+				t.Errorf("instructions ask to confirm a code the server did not send:\n%s", out.String())
+			}
+		})
+	}
+}
@@ -1,12 +1,10 @@
 package auth
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/Bader-GmbH/iot-cli/internal/config"
@@ -22,42 +20,60 @@ type Credentials struct {
 	TenantID     string    `json:"tenantId,omitempty"`
 }
 
+// refreshBuffer is how long before expiry an access token is refreshed
+const refreshBuffer = 5 * time.Minute
+
 // TokenStore handles credential storage and retrieval
 type TokenStore struct {
-	credentialsPath string
+	backend       CredentialBackend
+	lockPath      string
+	cognitoConfig CognitoConfig
+	verifier      *Verifier
 }
 
-// NewTokenStore creates a new TokenStore
-func NewTokenStore() (*TokenStore, error) {
-	credPath, err := config.GetCredentialsPath()
+// NewTokenStore creates a new TokenStore for the given profile, using the
+// credential backend the profile selects
+func NewTokenStore(profile *config.Profile) (*TokenStore, error) {
+	credPath, err := profile.CredentialsPath()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get credentials path: %w", err)
 	}
 
+	profileDir, err := config.GetProfileDir(profile.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile directory: %w", err)
+	}
+
+	backend, err := NewCredentialBackend(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	cognitoConfig := CognitoConfigFromProfile(profile)
+
 	return &TokenStore{
-		credentialsPath: credPath,
+		backend:       backend,
+		lockPath:      credPath + ".lock",
+		cognitoConfig: cognitoConfig,
+		verifier:      NewVerifier(cognitoConfig, profileDir),
 	}, nil
 }
 
-// Save stores credentials to disk
-func (t *TokenStore) Save(creds *Credentials) error {
-	// Ensure directory exists
-	dir := filepath.Dir(t.credentialsPath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	// Extract user info from ID token
-	if creds.IDToken != "" {
-		claims, err := parseJWTClaims(creds.IDToken)
-		if err == nil {
-			if email, ok := claims["email"].(string); ok {
-				creds.Email = email
-			}
-			if tenantID, ok := claims["custom:tenant_id"].(string); ok {
-				creds.TenantID = tenantID
-			}
-		}
+// Save verifies the tokens and stores the credentials
+func (t *TokenStore) Save(ctx context.Context, creds *Credentials) error {
+	lock, err := acquireLock(ctx, t.lockPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return t.save(ctx, creds)
+}
+
+// save verifies and stores credentials in the backend; the caller must hold the lock
+func (t *TokenStore) save(ctx context.Context, creds *Credentials) error {
+	if err := t.applyClaims(ctx, creds); err != nil {
+		return err
 	}
 
 	data, err := json.MarshalIndent(creds, "", "  ")
@@ -65,22 +81,17 @@ func (t *TokenStore) Save(creds *Credentials) error {
 		return fmt.Errorf("failed to marshal credentials: %w", err)
 	}
 
-	// Write with restricted permissions (owner read/write only)
-	if err := os.WriteFile(t.credentialsPath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write credentials: %w", err)
-	}
-
-	return nil
+	return t.backend.Store(data)
 }
 
 // Load retrieves stored credentials
 func (t *TokenStore) Load() (*Credentials, error) {
-	data, err := os.ReadFile(t.credentialsPath)
+	data, err := t.backend.Get()
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, ErrNoCredentials) {
 			return nil, fmt.Errorf("not logged in (run 'iot auth login')")
 		}
-		return nil, fmt.Errorf("failed to read credentials: %w", err)
+		return nil, err
 	}
 
 	var creds Credentials
@@ -93,45 +104,132 @@ func (t *TokenStore) Load() (*Credentials, error) {
 
 // Delete removes stored credentials
 func (t *TokenStore) Delete() error {
-	err := os.Remove(t.credentialsPath)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete credentials: %w", err)
+	lock, err := acquireLock(context.Background(), t.lockPath)
+	if err != nil {
+		return err
 	}
-	return nil
+	defer lock.Unlock()
+
+	return t.backend.Erase()
 }
 
-// IsLoggedIn checks if valid credentials exist
-func (t *TokenStore) IsLoggedIn() bool {
+// Revoke invalidates the stored session on the server. The refresh token is
+// revoked, which also invalidates the tokens issued with it. With global set,
+// the user is signed out of every session on every device instead. The
+// local credentials are left in place; remove them with Delete.
+func (t *TokenStore) Revoke(ctx context.Context, global bool) error {
+	cognito, err := NewCognitoClient(t.cognitoConfig)
+	if err != nil {
+		return err
+	}
+
+	if global {
+		// Global sign-out is authorized by a valid access token
+		accessToken, err := t.GetAccessToken(ctx)
+		if err != nil {
+			return err
+		}
+		return cognito.GlobalSignOut(ctx, accessToken)
+	}
+
 	creds, err := t.Load()
 	if err != nil {
-		return false
+		return err
+	}
+	if creds.RefreshToken == "" {
+		return nil
 	}
 
-	// Check if tokens are expired
-	if time.Now().After(creds.ExpiresAt) {
-		// Could try to refresh here, but for simplicity just return false
+	return cognito.RevokeToken(ctx, creds.RefreshToken)
+}
+
+// IsLoggedIn checks if valid credentials exist. An expired access token is
+// refreshed, so a session counts as long as its refresh token is valid.
+func (t *TokenStore) IsLoggedIn(ctx context.Context) bool {
+	creds, err := t.Load()
+	if err != nil {
 		return false
 	}
 
+	if _, err := t.GetAccessToken(ctx); err != nil {
+		// A refresh that fails while the access token still works is no logout
+		return time.Now().Before(creds.ExpiresAt)
+	}
+
 	return true
 }
 
 // GetAccessToken returns the current access token, refreshing if needed
-func (t *TokenStore) GetAccessToken() (string, error) {
+func (t *TokenStore) GetAccessToken(ctx context.Context) (string, error) {
 	creds, err := t.Load()
 	if err != nil {
 		return "", err
 	}
 
-	// Check if token is expired (with 5 minute buffer)
-	if time.Now().Add(5 * time.Minute).After(creds.ExpiresAt) {
-		// TODO: Implement token refresh
-		return "", fmt.Errorf("token expired, please login again")
+	if !needsRefresh(creds) {
+		return creds.AccessToken, nil
+	}
+
+	// Hold the lock across reload, refresh and save so that parallel
+	// processes don't race each other and overwrite a fresh token
+	lock, err := acquireLock(ctx, t.lockPath)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+
+	// Another process may have refreshed while we were waiting
+	creds, err = t.Load()
+	if err != nil {
+		return "", err
+	}
+	if !needsRefresh(creds) {
+		return creds.AccessToken, nil
+	}
+
+	creds, err = t.refresh(ctx, creds)
+	if err != nil {
+		return "", err
 	}
 
 	return creds.AccessToken, nil
 }
 
+// refresh exchanges the refresh token for new tokens and persists them.
+// The caller must hold the lock.
+func (t *TokenStore) refresh(ctx context.Context, creds *Credentials) (*Credentials, error) {
+	if creds.RefreshToken == "" {
+		return nil, fmt.Errorf("token expired, please login again")
+	}
+
+	cognito, err := NewCognitoClient(t.cognitoConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := cognito.RefreshTokens(ctx, creds.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w (run 'iot auth login')", err)
+	}
+
+	refreshed := &Credentials{
+		AccessToken:  result.AccessToken,
+		IDToken:      result.IDToken,
+		RefreshToken: result.RefreshToken,
+	}
+
+	if err := t.save(ctx, refreshed); err != nil {
+		return nil, err
+	}
+
+	return refreshed, nil
+}
+
+// needsRefresh reports whether the access token expires within refreshBuffer
+func needsRefresh(creds *Credentials) bool {
+	return time.Now().Add(refreshBuffer).After(creds.ExpiresAt)
+}
+
 // GetTenantID returns the tenant ID from stored credentials
 func (t *TokenStore) GetTenantID() (string, error) {
 	creds, err := t.Load()
@@ -146,23 +244,51 @@ func (t *TokenStore) GetTenantID() (string, error) {
 	return creds.TenantID, nil
 }
 
-// parseJWTClaims extracts claims from a JWT token without verification
-func parseJWTClaims(token string) (map[string]interface{}, error) {
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid JWT format")
+// Claims returns the verified claims of the stored ID and access tokens,
+// refreshing the tokens first if they are about to expire
+func (t *TokenStore) Claims(ctx context.Context) (idClaims, accessClaims *Claims, err error) {
+	if _, err := t.GetAccessToken(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	creds, err := t.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idClaims, err = t.verifier.Verify(ctx, creds.IDToken, TokenUseID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	accessClaims, err = t.verifier.Verify(ctx, creds.AccessToken, TokenUseAccess)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid access token: %w", err)
+	}
+
+	return idClaims, accessClaims, nil
+}
+
+// applyClaims verifies the tokens against the user pool and takes the user
+// info and expiry from their claims rather than trusting the caller
+func (t *TokenStore) applyClaims(ctx context.Context, creds *Credentials) error {
+	if creds.IDToken == "" {
+		return fmt.Errorf("credentials contain no ID token")
 	}
 
-	// Decode the payload (second part)
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	idClaims, err := t.verifier.Verify(ctx, creds.IDToken, TokenUseID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode JWT payload: %w", err)
+		return fmt.Errorf("invalid ID token: %w", err)
 	}
 
-	var claims map[string]interface{}
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		return nil, fmt.Errorf("failed to parse JWT claims: %w", err)
+	accessClaims, err := t.verifier.Verify(ctx, creds.AccessToken, TokenUseAccess)
+	if err != nil {
+		return fmt.Errorf("invalid access token: %w", err)
 	}
 
-	return claims, nil
+	creds.Email = idClaims.Email
+	creds.TenantID = idClaims.TenantID
+	creds.ExpiresAt = accessClaims.Expiry()
+
+	return nil
 }
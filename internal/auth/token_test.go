@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock_Exclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json.lock")
+
+	first, err := acquireLock(context.Background(), path)
+	if err != nil {
+		t.Fatalf("acquireLock() unexpected error: %v", err)
+	}
+
+	// A second lock must wait while the first is held
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if second, err := acquireLock(ctx, path); err == nil {
+		second.Unlock()
+		t.Fatal("acquireLock() succeeded while lock was held")
+	}
+
+	first.Unlock()
+
+	second, err := acquireLock(context.Background(), path)
+	if err != nil {
+		t.Fatalf("acquireLock() after unlock unexpected error: %v", err)
+	}
+	second.Unlock()
+}
+
+func TestTokenStore_GetAccessToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		refresh   string
+		wantErr   bool
+		wantToken bool
+	}{
+		{
+			name:      "valid token",
+			ttl:       time.Hour,
+			wantToken: true,
+		},
+		{
+			name:    "expiring without refresh token",
+			ttl:     time.Minute,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := &TokenStore{
+				backend:       &fileBackend{path: filepath.Join(dir, "credentials.json")},
+				lockPath:      filepath.Join(dir, "credentials.json.lock"),
+				cognitoConfig: issuer.config(),
+				verifier:      NewVerifier(issuer.config(), dir),
+			}
+
+			accessToken := issuer.token(t, TokenUseAccess, tt.ttl, nil)
+			creds := &Credentials{
+				AccessToken:  accessToken,
+				IDToken:      issuer.token(t, TokenUseID, tt.ttl, map[string]interface{}{"custom:tenant_id": "tenant-1"}),
+				RefreshToken: tt.refresh,
+				// Expiry and tenant must come from the verified claims, not from here
+				ExpiresAt: time.Now().Add(24 * time.Hour),
+				TenantID:  "spoofed",
+			}
+			if err := store.Save(context.Background(), creds); err != nil {
+				t.Fatalf("Save() unexpected error: %v", err)
+			}
+
+			if tenantID, _ := store.GetTenantID(); tenantID != "tenant-1" {
+				t.Errorf("GetTenantID() = %q, want %q", tenantID, "tenant-1")
+			}
+
+			got, err := store.GetAccessToken(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("GetAccessToken() expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAccessToken() unexpected error: %v", err)
+			}
+			if got != accessToken {
+				t.Errorf("GetAccessToken() = %q, want the stored access token", got)
+			}
+		})
+	}
+}
+
+// newRefreshStore creates a token store whose user pool answers refreshes
+// with tokens valid for ttl
+func newRefreshStore(t *testing.T, issuer *testIssuer, ttl time.Duration) *TokenStore {
+	t.Helper()
+
+	cognito := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.Header.Get("X-Amz-Target"), ".InitiateAuth") {
+			t.Errorf("unexpected target %q", r.Header.Get("X-Amz-Target"))
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"AuthenticationResult": map[string]interface{}{
+				"AccessToken": issuer.token(t, TokenUseAccess, ttl, nil),
+				"IdToken":     issuer.token(t, TokenUseID, ttl, nil),
+				"ExpiresIn":   int(ttl.Seconds()),
+			},
+		})
+	}))
+	t.Cleanup(cognito.Close)
+
+	cfg := issuer.config()
+	cfg.Region = "eu-central-1"
+	cfg.Endpoint = cognito.URL
+
+	dir := t.TempDir()
+	return &TokenStore{
+		backend:       &fileBackend{path: filepath.Join(dir, "credentials.json")},
+		lockPath:      filepath.Join(dir, "credentials.json.lock"),
+		cognitoConfig: cfg,
+		verifier:      NewVerifier(cfg, dir),
+	}
+}
+
+func TestTokenStore_IsLoggedIn(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	tests := []struct {
+		name    string
+		refresh string
+		want    bool
+	}{
+		{name: "expired with refresh token", refresh: "refresh", want: true},
+		{name: "expired without refresh token", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newRefreshStore(t, issuer, time.Hour)
+			// Save rejects expired tokens, so the credentials are stored directly
+			data, _ := json.Marshal(&Credentials{
+				AccessToken:  "expired",
+				IDToken:      "expired",
+				RefreshToken: tt.refresh,
+				ExpiresAt:    time.Now().Add(-time.Minute),
+			})
+			if err := store.backend.Store(data); err != nil {
+				t.Fatalf("Store() unexpected error: %v", err)
+			}
+
+			if got := store.IsLoggedIn(context.Background()); got != tt.want {
+				t.Errorf("IsLoggedIn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenStore_ConcurrentRefreshAndLoad(t *testing.T) {
+	issuer := newTestIssuer(t)
+	// Refreshed tokens are due for refresh right away, so every
+	// GetAccessToken rewrites the credentials
+	store := newRefreshStore(t, issuer, time.Minute)
+	err := store.Save(context.Background(), &Credentials{
+		AccessToken:  issuer.token(t, TokenUseAccess, time.Minute, nil),
+		IDToken:      issuer.token(t, TokenUseID, time.Minute, nil),
+		RefreshToken: "refresh",
+	})
+	if err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := store.GetAccessToken(context.Background()); err != nil {
+					t.Errorf("GetAccessToken() unexpected error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := store.Load(); err != nil {
+					t.Errorf("Load() during refresh unexpected error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+}
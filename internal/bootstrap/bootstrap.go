@@ -0,0 +1,213 @@
+// Package bootstrap generates provisioning scripts that install the device
+// agent and enroll a machine with a registration token.
+package bootstrap
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Formats lists the kinds of provisioning scripts Render writes
+var Formats = []string{"sh", "cloud-init", "systemd"}
+
+// Locations of the agent on the provisioned machine
+const (
+	AgentBinary  = "/usr/local/bin/b-agent"
+	AgentEnvFile = "/etc/b-agent/agent.env"
+	AgentUnit    = "/etc/systemd/system/b-agent.service"
+)
+
+// Config is what the agent needs to enroll
+type Config struct {
+	APIURL  string
+	TokenID string // Named in the script's header
+	Token   string
+	Group   string // Group the devices join, named in the script's header
+}
+
+// script is the data of the templates
+type script struct {
+	Config
+	Header   string // Comment lines describing the script
+	AgentURL string // Download URL of the agent without the architecture
+	Install  string // Shell commands that install the agent from $AGENT_URL
+	Env      []string
+	Unit     string
+}
+
+// Render writes a provisioning script in the given format
+func Render(w io.Writer, format string, cfg Config) error {
+	tmpl, ok := templates[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(Formats, ", "))
+	}
+	// Values end up in environment files and YAML blocks unquoted
+	for name, value := range map[string]string{"API URL": cfg.APIURL, "registration token": cfg.Token} {
+		if value == "" || strings.IndexFunc(value, unsafe) >= 0 {
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+	}
+
+	header := fmt.Sprintf("Enrolls this machine with the Bader IoT platform at %s\nusing registration token %s", cfg.APIURL, cfg.TokenID)
+	if cfg.Group != "" {
+		header += " (group " + cfg.Group + ")"
+	}
+	header += ".\nGenerated by 'iot device bootstrap'. It contains the token, keep it private."
+
+	s := script{
+		Config:   cfg,
+		Header:   "# " + strings.ReplaceAll(header, "\n", "\n# "),
+		AgentURL: strings.TrimSuffix(cfg.APIURL, "/") + "/api/releases/agent/latest/b-agent-linux-",
+		Install:  install,
+		Env: []string{
+			"B_AGENT_API_URL=" + cfg.APIURL,
+			"B_AGENT_REGISTRATION_TOKEN=" + cfg.Token,
+		},
+	}
+
+	// The unit of the systemd format carries the settings itself
+	environment := "EnvironmentFile=" + AgentEnvFile
+	if format == "systemd" {
+		var lines []string
+		for _, e := range s.Env {
+			lines = append(lines, "Environment="+systemdQuote(e))
+		}
+		environment = strings.Join(lines, "\n")
+	}
+	s.Unit = fmt.Sprintf(unit, environment, AgentBinary)
+
+	return tmpl.Execute(w, s)
+}
+
+// unsafe reports whether a character needs quoting in a script
+func unsafe(r rune) bool {
+	return unicode.IsSpace(r) || !unicode.IsPrint(r) || strings.ContainsRune("'\"\\$`", r)
+}
+
+// unit is the agent's systemd unit, given its environment settings and binary
+const unit = `[Unit]
+Description=Bader IoT device agent
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+%s
+ExecStart=%s
+Restart=always
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// install downloads the agent for the machine's architecture from $AGENT_URL
+const install = `case "$(uname -m)" in
+	x86_64 | amd64) ARCH=amd64 ;;
+	aarch64 | arm64) ARCH=arm64 ;;
+	armv7* | armv6*) ARCH=arm ;;
+	*)
+		echo "Unsupported architecture: $(uname -m)" >&2
+		exit 1
+		;;
+esac
+
+echo "Installing the agent for $ARCH..."
+tmp=$(mktemp)
+if command -v curl >/dev/null 2>&1; then
+	curl -fsSL "$AGENT_URL$ARCH" -o "$tmp"
+else
+	wget -qO "$tmp" "$AGENT_URL$ARCH"
+fi
+mkdir -p "$(dirname ` + AgentBinary + `)"
+mv "$tmp" ` + AgentBinary + `
+chmod 0755 ` + AgentBinary + `
+`
+
+// shQuote quotes a value for the shell
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// systemdQuote quotes a value for a unit file setting, escaping specifiers
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(s)
+	return `"` + s + `"`
+}
+
+// indent prefixes every non-empty line with n spaces and drops the final
+// line break
+func indent(n int, s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Repeat(" ", n) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var funcs = template.FuncMap{"sh": shQuote, "indent": indent}
+
+var templates = map[string]*template.Template{
+	"sh": template.Must(template.New("sh").Funcs(funcs).Parse(`#!/bin/sh
+{{.Header}}
+set -eu
+
+API_URL={{sh .APIURL}}
+REGISTRATION_TOKEN={{sh .Token}}
+AGENT_URL="${AGENT_URL:-{{.AgentURL}}}"
+
+if [ "$(id -u)" -ne 0 ]; then
+	echo "Run this script as root" >&2
+	exit 1
+fi
+
+{{.Install}}
+mkdir -p /etc/b-agent
+(
+	umask 077
+	cat >` + AgentEnvFile + ` <<EOF
+B_AGENT_API_URL=$API_URL
+B_AGENT_REGISTRATION_TOKEN=$REGISTRATION_TOKEN
+EOF
+)
+
+cat >` + AgentUnit + ` <<'EOF'
+{{.Unit}}EOF
+
+systemctl daemon-reload
+systemctl enable --now b-agent.service
+echo "The agent is running. Approve the device with 'iot device approve'."
+`)),
+
+	"cloud-init": template.Must(template.New("cloud-init").Funcs(funcs).Parse(`#cloud-config
+{{.Header}}
+write_files:
+  - path: ` + AgentEnvFile + `
+    permissions: "0600"
+    content: |
+{{- range .Env}}
+      {{.}}
+{{- end}}
+  - path: ` + AgentUnit + `
+    permissions: "0644"
+    content: |
+{{indent 6 .Unit}}
+runcmd:
+  - |
+    set -eu
+    AGENT_URL={{sh .AgentURL}}
+{{indent 4 .Install}}
+  - systemctl daemon-reload
+  - systemctl enable --now b-agent.service
+`)),
+
+	"systemd": template.Must(template.New("systemd").Parse(`{{.Header}}
+#
+# Install as ` + AgentUnit + ` in an image that contains
+# ` + AgentBinary + ` and enable it; the agent enrolls on first boot.
+{{.Unit}}`)),
+}
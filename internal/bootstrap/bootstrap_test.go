@@ -0,0 +1,128 @@
+package bootstrap
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var testConfig = Config{APIURL: "https://api.example.com", TokenID: "rt-1", Token: "rtk_secret%1", Group: "line-1"}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{
+			format: "sh",
+			want: []string{
+				"#!/bin/sh\n",
+				"registration token rt-1 (group line-1)",
+				"REGISTRATION_TOKEN='rtk_secret%1'",
+				"https://api.example.com/api/releases/agent/latest/b-agent-linux-",
+				"EnvironmentFile=" + AgentEnvFile,
+				"systemctl enable --now b-agent.service",
+			},
+		},
+		{
+			format: "cloud-init",
+			want: []string{
+				"#cloud-config\n",
+				"      B_AGENT_REGISTRATION_TOKEN=rtk_secret%1\n",
+				"      EnvironmentFile=" + AgentEnvFile,
+			},
+		},
+		{
+			format: "systemd",
+			want: []string{
+				"# Install as " + AgentUnit,
+				`Environment="B_AGENT_REGISTRATION_TOKEN=rtk_secret%%1"`,
+				"ExecStart=" + AgentBinary,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b strings.Builder
+			if err := Render(&b, tt.format, testConfig); err != nil {
+				t.Fatalf("Render() unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("Render() output lacks %q:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRender_ShellSyntax(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell to check the script with")
+	}
+	var b strings.Builder
+	if err := Render(&b, "sh", testConfig); err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+	cmd := exec.Command(sh, "-n")
+	cmd.Stdin = strings.NewReader(b.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("sh -n: %v\n%s", err, out)
+	}
+}
+
+func TestRender_CloudConfig(t *testing.T) {
+	var b strings.Builder
+	if err := Render(&b, "cloud-init", testConfig); err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+
+	var config struct {
+		WriteFiles []struct {
+			Path        string `yaml:"path"`
+			Permissions string `yaml:"permissions"`
+			Content     string `yaml:"content"`
+		} `yaml:"write_files"`
+		RunCmd []string `yaml:"runcmd"`
+	}
+	if err := yaml.Unmarshal([]byte(b.String()), &config); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, b.String())
+	}
+	if len(config.WriteFiles) != 2 || config.WriteFiles[0].Path != AgentEnvFile || config.WriteFiles[0].Permissions != "0600" {
+		t.Fatalf("write_files = %+v, want the environment file first", config.WriteFiles)
+	}
+	if want := "B_AGENT_API_URL=https://api.example.com\nB_AGENT_REGISTRATION_TOKEN=rtk_secret%1\n"; config.WriteFiles[0].Content != want {
+		t.Errorf("environment file = %q, want %q", config.WriteFiles[0].Content, want)
+	}
+	if !strings.HasPrefix(config.WriteFiles[1].Content, "[Unit]\n") {
+		t.Errorf("unit file = %q, want a systemd unit", config.WriteFiles[1].Content)
+	}
+	if len(config.RunCmd) != 3 || !strings.Contains(config.RunCmd[0], `mv "$tmp" `+AgentBinary) {
+		t.Errorf("runcmd = %q, want the agent installed first", config.RunCmd)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		config Config
+	}{
+		{name: "unknown format", format: "ansible", config: testConfig},
+		{name: "no token", format: "sh", config: Config{APIURL: "https://api.example.com"}},
+		{name: "token with a quote", format: "sh", config: Config{APIURL: "https://api.example.com", Token: "a'b"}},
+		{name: "URL with a line break", format: "systemd", config: Config{APIURL: "https://api.example.com\nExecStartPre=/bin/evil", Token: "t"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Render(&strings.Builder{}, tt.format, tt.config); err == nil {
+				t.Error("Render() expected an error")
+			}
+		})
+	}
+}
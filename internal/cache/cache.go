@@ -0,0 +1,133 @@
+// Package cache stores API responses on disk so that repeated reads can be
+// answered locally or revalidated cheaply, and so that the last known state
+// stays available when the API cannot be reached.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry is a stored response
+type Entry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"storedAt"`
+}
+
+// ETag returns the entity tag the response was served with
+func (e *Entry) ETag() string {
+	return e.Header.Get("ETag")
+}
+
+// LastModified returns the Last-Modified date the response was served with
+func (e *Entry) LastModified() string {
+	return e.Header.Get("Last-Modified")
+}
+
+// Fresh reports whether the entry is younger than ttl
+func (e *Entry) Fresh(ttl time.Duration, now time.Time) bool {
+	return ttl > 0 && now.Sub(e.StoredAt) < ttl
+}
+
+// Store keeps entries in a directory. Entries are grouped by scope so that
+// all responses below an API path can be invalidated at once.
+type Store struct {
+	dir string
+}
+
+// Open returns a store keeping its entries in dir. The directory is created
+// when the first entry is written.
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory holding the entries
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Get returns the entry stored under key, or nil if there is none
+func (s *Store) Get(scope, key string) (*Entry, error) {
+	data, err := os.ReadFile(s.path(scope, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// A damaged entry is a miss; it is replaced by the next Put
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Put stores an entry under key. The file is replaced atomically, so
+// concurrent readers never see a partial entry.
+func (s *Store) Put(scope, key string, entry *Entry) error {
+	path := s.path(scope, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Invalidate removes all entries of a scope
+func (s *Store) Invalidate(scope string) error {
+	if err := os.RemoveAll(filepath.Join(s.dir, hash(scope))); err != nil {
+		return fmt.Errorf("failed to invalidate cache: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all entries
+func (s *Store) Clear() error {
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding an entry
+func (s *Store) path(scope, key string) string {
+	return filepath.Join(s.dir, hash(scope), hash(key)+".json")
+}
+
+// hash turns a scope or key into a file name
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
@@ -15,12 +15,14 @@ var Defaults = struct {
 	APIURL        string
 	AuthURL       string
 	CognitoRegion string
+	UserPoolID    string
 	ClientID      string
 }{
 	APIURL:        "https://api.iot.bader.solutions",
 	AuthURL:       "https://auth.iot.bader.solutions",
 	CognitoRegion: "eu-central-1",
-	ClientID:      "", // Will be set based on Cognito app client
+	UserPoolID:    "eu-central-1_SyQyrM9xc",
+	ClientID:      "70sngp1h120tni8csqp3s683an",
 }
 
 // GetConfigDir returns the configuration directory path based on OS
@@ -76,11 +78,37 @@ func EnsureConfigDir() (string, error) {
 	return configDir, nil
 }
 
-// GetCredentialsPath returns the path to the credentials file
-func GetCredentialsPath() (string, error) {
+// GetProfileDir returns the directory holding a profile's state. The default
+// profile lives directly in the config directory so that existing logins keep working.
+func GetProfileDir(profile string) (string, error) {
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, "credentials.json"), nil
+	if profile == "" || profile == DefaultProfileName {
+		return configDir, nil
+	}
+	return filepath.Join(configDir, "profiles", profile), nil
+}
+
+// GetCredentialsPath returns the path to the credentials file of a profile
+func GetCredentialsPath(profile string) (string, error) {
+	profileDir, err := GetProfileDir(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profileDir, "credentials.json"), nil
+}
+
+// GetCacheDir returns the directory holding a profile's cached API responses.
+// Without a profile it returns the directory holding the caches of all profiles.
+func GetCacheDir(profile string) (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if profile == "" {
+		return filepath.Join(configDir, "cache"), nil
+	}
+	return filepath.Join(configDir, "cache", profile), nil
 }
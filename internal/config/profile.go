@@ -0,0 +1,252 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfileName is the profile used when none is selected
+const DefaultProfileName = "default"
+
+// APIKeyEnv is the environment variable that overrides a profile's API key
+const APIKeyEnv = "IOT_API_KEY"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Profile holds the settings for one platform environment or account
+type Profile struct {
+	Name    string          `yaml:"-" json:"name"`
+	APIURL  string          `yaml:"api_url,omitempty" json:"apiUrl,omitempty"`
+	AuthURL string          `yaml:"auth_url,omitempty" json:"authUrl,omitempty"`
+	Cognito CognitoSettings `yaml:"cognito,omitempty" json:"cognito,omitempty"`
+
+	// CredentialStore selects where credentials are kept: file (default),
+	// encrypted-file or helper
+	CredentialStore string `yaml:"credential_store,omitempty" json:"credentialStore,omitempty"`
+	// CredentialHelper is the git-style helper used by the helper store
+	CredentialHelper string `yaml:"credential_helper,omitempty" json:"credentialHelper,omitempty"`
+
+	// Tenant selects the tenant requests are sent for; empty uses the
+	// tenant of the login
+	Tenant string `yaml:"tenant,omitempty" json:"tenant,omitempty"`
+
+	// Retry overrides the global retry settings for this profile
+	Retry RetrySettings `yaml:"retry,omitempty" json:"retry,omitempty"`
+
+	// Transport overrides the global TLS and proxy settings for this profile
+	Transport TransportSettings `yaml:"transport,omitempty" json:"transport,omitempty"`
+
+	// APIKey authenticates as a service account instead of a logged-in user
+	APIKey string `yaml:"api_key,omitempty" json:"-"`
+}
+
+// CognitoSettings holds the Cognito user pool settings of a profile
+type CognitoSettings struct {
+	Region     string `yaml:"region,omitempty" json:"region,omitempty"`
+	UserPoolID string `yaml:"user_pool_id,omitempty" json:"userPoolId,omitempty"`
+	ClientID   string `yaml:"client_id,omitempty" json:"clientId,omitempty"`
+	Endpoint   string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Issuer     string `yaml:"issuer,omitempty" json:"issuer,omitempty"`
+}
+
+// RetrySettings controls how transient API failures are retried
+type RetrySettings struct {
+	MaxRetries *int          `yaml:"max_retries,omitempty" json:"maxRetries,omitempty"`
+	MaxDelay   time.Duration `yaml:"max_delay,omitempty" json:"maxDelay,omitempty"`
+}
+
+// TransportSettings configure TLS and proxies for outbound connections
+type TransportSettings struct {
+	CABundle   string              `yaml:"ca_bundle,omitempty" json:"caBundle,omitempty"`
+	ClientCert string              `yaml:"client_cert,omitempty" json:"clientCert,omitempty"`
+	ClientKey  string              `yaml:"client_key,omitempty" json:"clientKey,omitempty"`
+	ProxyURL   string              `yaml:"proxy_url,omitempty" json:"proxyUrl,omitempty"`
+	NoProxy    string              `yaml:"no_proxy,omitempty" json:"noProxy,omitempty"`
+	Pins       map[string][]string `yaml:"pins,omitempty" json:"pins,omitempty"`
+}
+
+// Merge returns s with the settings that override sets replaced
+func (s TransportSettings) Merge(override TransportSettings) TransportSettings {
+	if override.CABundle != "" {
+		s.CABundle = override.CABundle
+	}
+	if override.ClientCert != "" || override.ClientKey != "" {
+		s.ClientCert = override.ClientCert
+		s.ClientKey = override.ClientKey
+	}
+	if override.ProxyURL != "" {
+		s.ProxyURL = override.ProxyURL
+		s.NoProxy = override.NoProxy
+	}
+	if len(override.Pins) > 0 {
+		s.Pins = override.Pins
+	}
+	return s
+}
+
+// WithDefaults returns a copy of the profile with empty settings filled in
+// from the built-in defaults
+func (p *Profile) WithDefaults() *Profile {
+	resolved := *p
+	if resolved.APIURL == "" {
+		resolved.APIURL = Defaults.APIURL
+	}
+	if resolved.AuthURL == "" {
+		resolved.AuthURL = Defaults.AuthURL
+	}
+	if resolved.Cognito.Region == "" {
+		resolved.Cognito.Region = Defaults.CognitoRegion
+	}
+	if resolved.Cognito.UserPoolID == "" {
+		resolved.Cognito.UserPoolID = Defaults.UserPoolID
+	}
+	if resolved.Cognito.ClientID == "" {
+		resolved.Cognito.ClientID = Defaults.ClientID
+	}
+	return &resolved
+}
+
+// CredentialsPath returns the path of the profile's credentials file
+func (p *Profile) CredentialsPath() (string, error) {
+	return GetCredentialsPath(p.Name)
+}
+
+// ValidateProfileName checks that a profile name is usable as a file name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// File is the parsed config.yaml. Keys that are not managed here are
+// preserved when the file is written back.
+type File struct {
+	CurrentProfile string
+	Profiles       map[string]*Profile
+
+	path string
+	raw  map[string]interface{}
+}
+
+// GetConfigPath returns the path to the default config file
+func GetConfigPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "config.yaml"), nil
+}
+
+// LoadFile reads the config file at path. A missing file yields an empty config.
+func LoadFile(path string) (*File, error) {
+	f := &File{
+		Profiles: make(map[string]*Profile),
+		path:     path,
+		raw:      make(map[string]interface{}),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, &f.raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if f.raw == nil {
+		f.raw = make(map[string]interface{})
+	}
+
+	var managed struct {
+		CurrentProfile string              `yaml:"current_profile"`
+		Profiles       map[string]*Profile `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &managed); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+
+	f.CurrentProfile = managed.CurrentProfile
+	for name, p := range managed.Profiles {
+		if p == nil {
+			p = &Profile{}
+		}
+		p.Name = name
+		f.Profiles[name] = p
+	}
+
+	return f, nil
+}
+
+// Save writes the config file back to disk
+func (f *File) Save() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if f.CurrentProfile != "" {
+		f.raw["current_profile"] = f.CurrentProfile
+	} else {
+		delete(f.raw, "current_profile")
+	}
+	if len(f.Profiles) > 0 {
+		f.raw["profiles"] = f.Profiles
+	} else {
+		delete(f.raw, "profiles")
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(f.raw); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(f.path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
+}
+
+// Current returns the name of the selected profile
+func (f *File) Current() string {
+	if f.CurrentProfile == "" {
+		return DefaultProfileName
+	}
+	return f.CurrentProfile
+}
+
+// Profile returns the named profile. The default profile always exists,
+// even when it has no entry in the config file.
+func (f *File) Profile(name string) (*Profile, error) {
+	if p, ok := f.Profiles[name]; ok {
+		return p, nil
+	}
+	if name == DefaultProfileName {
+		return &Profile{Name: DefaultProfileName}, nil
+	}
+	return nil, fmt.Errorf("profile %q not found (run 'iot profile list')", name)
+}
+
+// ProfileNames returns all known profile names, sorted, including the default profile
+func (f *File) ProfileNames() []string {
+	names := []string{DefaultProfileName}
+	for name := range f.Profiles {
+		if name != DefaultProfileName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile_SaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	initial := "api_url: https://legacy.example.com\nverbose: true\n"
+	if err := os.WriteFile(path, []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() unexpected error: %v", err)
+	}
+
+	f.Profiles["staging"] = &Profile{Name: "staging", APIURL: "https://staging.example.com"}
+	f.CurrentProfile = "staging"
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	reloaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() unexpected error: %v", err)
+	}
+
+	if reloaded.Current() != "staging" {
+		t.Errorf("Current() = %q, want %q", reloaded.Current(), "staging")
+	}
+
+	p, err := reloaded.Profile("staging")
+	if err != nil {
+		t.Fatalf("Profile() unexpected error: %v", err)
+	}
+	if p.Name != "staging" || p.APIURL != "https://staging.example.com" {
+		t.Errorf("Profile() = %+v, want staging with its API URL", p)
+	}
+
+	// Keys not managed by File must survive a save
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "legacy.example.com") || !strings.Contains(string(data), "verbose: true") {
+		t.Errorf("Save() dropped unmanaged keys:\n%s", data)
+	}
+}
+
+func TestFile_Profile(t *testing.T) {
+	f, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadFile() unexpected error: %v", err)
+	}
+
+	if f.Current() != DefaultProfileName {
+		t.Errorf("Current() = %q, want %q", f.Current(), DefaultProfileName)
+	}
+
+	if _, err := f.Profile(DefaultProfileName); err != nil {
+		t.Errorf("Profile(%q) unexpected error: %v", DefaultProfileName, err)
+	}
+
+	if _, err := f.Profile("unknown"); err == nil {
+		t.Error("Profile(\"unknown\") expected error, got nil")
+	}
+}
+
+func TestProfile_WithDefaults(t *testing.T) {
+	p := (&Profile{Name: "custom", APIURL: "https://custom.example.com"}).WithDefaults()
+
+	if p.APIURL != "https://custom.example.com" {
+		t.Errorf("APIURL = %q, want custom URL", p.APIURL)
+	}
+	if p.AuthURL != Defaults.AuthURL {
+		t.Errorf("AuthURL = %q, want %q", p.AuthURL, Defaults.AuthURL)
+	}
+	if p.Cognito.ClientID != Defaults.ClientID {
+		t.Errorf("Cognito.ClientID = %q, want %q", p.Cognito.ClientID, Defaults.ClientID)
+	}
+}
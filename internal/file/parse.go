@@ -8,12 +8,12 @@ import (
 
 // RemotePath represents a parsed device:path reference
 type RemotePath struct {
-	DeviceID string
-	Path     string
+	Device string // Device ID or name, resolved by the caller
+	Path   string
 }
 
 // ParseRemotePath parses a string in the format "device:path"
-// Returns the device ID and remote path
+// Returns the device reference and remote path
 func ParseRemotePath(s string) (*RemotePath, error) {
 	// Find the colon separator
 	idx := strings.Index(s, ":")
@@ -21,11 +21,11 @@ func ParseRemotePath(s string) (*RemotePath, error) {
 		return nil, fmt.Errorf("invalid remote path %q: expected format device:path", s)
 	}
 
-	deviceID := s[:idx]
+	device := s[:idx]
 	path := s[idx+1:]
 
-	if deviceID == "" {
-		return nil, fmt.Errorf("invalid remote path %q: device ID cannot be empty", s)
+	if device == "" {
+		return nil, fmt.Errorf("invalid remote path %q: device cannot be empty", s)
 	}
 
 	if path == "" {
@@ -38,11 +38,25 @@ func ParseRemotePath(s string) (*RemotePath, error) {
 	}
 
 	return &RemotePath{
-		DeviceID: deviceID,
-		Path:     path,
+		Device: device,
+		Path:   path,
 	}, nil
 }
 
+// ParseSelectedRemotePath parses a remote path in the format ":path", used
+// when the devices are selected otherwise, e.g. by label. The returned
+// device is empty.
+func ParseSelectedRemotePath(s string) (*RemotePath, error) {
+	path, ok := strings.CutPrefix(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid remote path %q: expected format :path when selecting devices", s)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid remote path %q: path must be absolute (start with /)", s)
+	}
+	return &RemotePath{Path: path}, nil
+}
+
 // IsRemotePath checks if a string looks like a remote path (contains :)
 func IsRemotePath(s string) bool {
 	// Must contain : but not be a Windows drive letter (C:\)
@@ -78,8 +78,8 @@ func TestParseRemotePath(t *testing.T) {
 				return
 			}
 
-			if result.DeviceID != tt.wantDevice {
-				t.Errorf("ParseRemotePath(%q).DeviceID = %q, want %q", tt.input, result.DeviceID, tt.wantDevice)
+			if result.Device != tt.wantDevice {
+				t.Errorf("ParseRemotePath(%q).Device = %q, want %q", tt.input, result.Device, tt.wantDevice)
 			}
 
 			if result.Path != tt.wantPath {
@@ -89,6 +89,38 @@ func TestParseRemotePath(t *testing.T) {
 	}
 }
 
+func TestParseSelectedRemotePath(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantPath string
+		wantErr  bool
+	}{
+		{input: ":/etc/app/", wantPath: "/etc/app/"},
+		{input: ":/var/log/app.log", wantPath: "/var/log/app.log"},
+		{input: "device-1:/etc/", wantErr: true},
+		{input: ":etc/app", wantErr: true},
+		{input: ":", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseSelectedRemotePath(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseSelectedRemotePath(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSelectedRemotePath(%q) unexpected error: %v", tt.input, err)
+			}
+			if result.Device != "" || result.Path != tt.wantPath {
+				t.Errorf("ParseSelectedRemotePath(%q) = %+v, want path %q", tt.input, result, tt.wantPath)
+			}
+		})
+	}
+}
+
 func TestIsRemotePath(t *testing.T) {
 	tests := []struct {
 		input string
@@ -2,6 +2,7 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -277,7 +278,7 @@ func uploadDirectory(ctx context.Context, client *api.Client, localPath, deviceI
 		// Create remote directory
 		if err := client.MkdirOnDevice(ctx, deviceID, destPath); err != nil {
 			// Directory might already exist, continue
-			if !strings.Contains(err.Error(), "exists") {
+			if !errors.Is(err, api.ErrConflict) {
 				return fmt.Errorf("failed to create directory: %w", err)
 			}
 		}
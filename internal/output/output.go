@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
@@ -54,3 +55,60 @@ func StatusText(online bool) string {
 	}
 	return "offline"
 }
+
+// TableWriter writes a table whose rows arrive in batches, such as the pages of
+// a listing. Column widths are taken from the headers and the first batch, so
+// rows are printed without waiting for the rest of the data.
+type TableWriter struct {
+	headers []string
+	widths  []int
+	started bool
+}
+
+// NewTableWriter creates a streaming table with the given headers
+func NewTableWriter(headers []string) *TableWriter {
+	return &TableWriter{headers: headers}
+}
+
+// Write prints a batch of rows, preceded by the headers on the first call
+func (t *TableWriter) Write(rows [][]string) {
+	if !t.started {
+		t.started = true
+		t.widths = make([]int, len(t.headers))
+		for _, row := range append([][]string{t.headers}, rows...) {
+			for i, cell := range row {
+				if i < len(t.widths) {
+					t.widths[i] = max(t.widths[i], utf8.RuneCountInString(cell))
+				}
+			}
+		}
+		t.writeRow(t.headers)
+	}
+
+	for _, row := range rows {
+		t.writeRow(row)
+	}
+}
+
+// writeRow prints one row padded to the column widths
+func (t *TableWriter) writeRow(row []string) {
+	var b strings.Builder
+	for i, cell := range row {
+		if i == len(row)-1 {
+			b.WriteString(cell)
+			break
+		}
+		b.WriteString(cell)
+		width := 0
+		if i < len(t.widths) {
+			width = t.widths[i]
+		}
+		b.WriteString(strings.Repeat(" ", max(width-utf8.RuneCountInString(cell), 0)+2))
+	}
+	fmt.Fprintln(os.Stdout, b.String())
+}
+
+// JSONLine writes data as a single line of JSON
+func JSONLine(data interface{}) error {
+	return json.NewEncoder(os.Stdout).Encode(data)
+}
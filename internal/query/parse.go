@@ -0,0 +1,271 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind classifies the tokens of a query
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokWord             // Field name or unquoted value
+	tokString           // Quoted value
+	tokOp               // Operator or punctuation
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// symbols are the operators and punctuation, longest first
+var symbols = []string{"&&", "||", "==", "!=", "!~", "<=", ">=", "=", "~", "<", ">", "!", "(", ")", ","}
+
+// wordBreaks end an unquoted word besides white space
+const wordBreaks = `()!,&|=<>~"`
+
+// lex splits a query into tokens
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case c == '"':
+			text, n, err := unquote(s[i:])
+			if err != nil {
+				return nil, &SyntaxError{Pos: i, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i += n
+			continue
+		}
+
+		matched := false
+		for _, sym := range symbols {
+			if strings.HasPrefix(s[i:], sym) {
+				tokens = append(tokens, token{kind: tokOp, text: sym, pos: i})
+				i += len(sym)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if c == '&' || c == '|' {
+			return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected %q, use %s%s", c, string(c), string(c))}
+		}
+
+		start := i
+		for i < len(s) {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if unicode.IsSpace(r) || strings.ContainsRune(wordBreaks, r) {
+				break
+			}
+			i += size
+		}
+		if i == start {
+			return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected %q", r)}
+		}
+		tokens = append(tokens, token{kind: tokWord, text: s[start:i], pos: start})
+	}
+	return append(tokens, token{kind: tokEOF, text: "end of query", pos: len(s)}), nil
+}
+
+// unquote reads a double-quoted string at the start of s, returning its
+// value and length. A backslash escapes the next character.
+func unquote(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// parser is a recursive descent parser over the tokens of a query:
+//
+//	or        = and { "||" and }
+//	and       = unary { "&&" unary }
+//	unary     = "!" unary | "(" or ")" | condition
+//	condition = field [ op value | [ "not" ] "in" "(" value { "," value } ")" ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes the given operator or fails
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected %q, found %q", op, t.text)}
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	if p.accept("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *parser) parseCondition() (node, error) {
+	name := p.next()
+	if name.kind != tokWord {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("expected a field, found %q", name.text)}
+	}
+	f, ok := lookupField(name.text)
+	if !ok {
+		return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("unknown field %q, use one of %s", name.text, strings.Join(FieldNames(), ", "))}
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && isComparison(t.text):
+		p.next()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return newCondition(f, t.text, []string{value}, name.pos)
+	case t.kind == tokWord && (t.text == "in" || t.text == "not"):
+		p.next()
+		op := "in"
+		if t.text == "not" {
+			if in := p.next(); in.kind != tokWord || in.text != "in" {
+				return nil, &SyntaxError{Pos: in.pos, Msg: fmt.Sprintf("expected \"in\", found %q", in.text)}
+			}
+			op = "not in"
+		}
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return newCondition(f, op, values, name.pos)
+	default:
+		return newCondition(f, "", nil, name.pos)
+	}
+}
+
+// parseList parses a parenthesized, comma-separated list of values
+func (p *parser) parseList() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	t := p.next()
+	if t.kind != tokWord && t.kind != tokString {
+		return "", &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected a value, found %q", t.text)}
+	}
+	return t.text, nil
+}
+
+// isComparison reports whether an operator compares a field with a value
+func isComparison(op string) bool {
+	switch op {
+	case "=", "==", "!=", "~", "!~", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,402 @@
+// Package query implements the query language that selects devices, e.g.
+//
+//	online && status=APPROVED && group in (line-1,line-2) && name~"^press-" && lastSeen>2h
+//
+// Conditions compare a device field with a value and are combined with &&,
+// || and !, grouped with parentheses. && binds tighter than ||. Labels are
+// tested as label.<key>; a bare label.<key> holds if the device has the label.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// SyntaxError reports where a query or label selector is malformed
+type SyntaxError struct {
+	Pos int // Byte offset in the query
+	Msg string
+
+	selector bool // Raised by ParseSelector
+}
+
+func (e *SyntaxError) Error() string {
+	what := "query"
+	if e.selector {
+		what = "selector"
+	}
+	return fmt.Sprintf("invalid %s at position %d: %s", what, e.Pos+1, e.Msg)
+}
+
+// Expr is a parsed query
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses a query
+func Parse(s string) (*Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return &Expr{src: s, root: root}, nil
+}
+
+// String returns the query as it was written
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Match reports whether a device satisfies the query at the given time
+func (e *Expr) Match(d *models.Device, now time.Time) bool {
+	return e.root.match(d, now)
+}
+
+// Tests reports whether the query has a condition on a field
+func (e *Expr) Tests(name string) bool {
+	var walk func(n node) bool
+	walk = func(n node) bool {
+		switch n := n.(type) {
+		case *andNode:
+			return walk(n.left) || walk(n.right)
+		case *orNode:
+			return walk(n.left) || walk(n.right)
+		case *notNode:
+			return walk(n.operand)
+		case *condition:
+			return strings.EqualFold(n.field.name, name)
+		}
+		return false
+	}
+	return walk(e.root)
+}
+
+// ServerFilters returns the online state ("online" or "offline") and group
+// name every matching device has, as far as the query requires them of all
+// devices. They can be passed to the API to narrow a listing before the
+// query is applied.
+func (e *Expr) ServerFilters() (status, group string) {
+	for _, n := range conjuncts(e.root) {
+		negated := false
+		if not, ok := n.(*notNode); ok {
+			n, negated = not.operand, true
+		}
+		c, ok := n.(*condition)
+		if !ok {
+			continue
+		}
+		switch {
+		case c.field.kind == kindBool && status == "":
+			// A condition on a boolean field holds for exactly one of its values
+			online := c.matchBool(true) != negated
+			if c.field.name == "offline" {
+				online = !online
+			}
+			status = "offline"
+			if online {
+				status = "online"
+			}
+		case c.field.name == "group" && c.op == "=" && len(c.values) == 1 && c.values[0] != "" && !negated && group == "":
+			group = c.values[0]
+		}
+	}
+	return status, group
+}
+
+// conjuncts returns the operands of the &&-chain at the root of a query
+func conjuncts(n node) []node {
+	if and, ok := n.(*andNode); ok {
+		return append(conjuncts(and.left), conjuncts(and.right)...)
+	}
+	return []node{n}
+}
+
+// node is an element of a parsed query
+type node interface {
+	match(d *models.Device, now time.Time) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n *andNode) match(d *models.Device, now time.Time) bool {
+	return n.left.match(d, now) && n.right.match(d, now)
+}
+
+type orNode struct{ left, right node }
+
+func (n *orNode) match(d *models.Device, now time.Time) bool {
+	return n.left.match(d, now) || n.right.match(d, now)
+}
+
+type notNode struct{ operand node }
+
+func (n *notNode) match(d *models.Device, now time.Time) bool {
+	return !n.operand.match(d, now)
+}
+
+// fieldKind determines which operators and values a field accepts
+type fieldKind int
+
+const (
+	kindBool     fieldKind = iota // online, offline
+	kindString                    // id, name
+	kindFold                      // Compared case-insensitively: group, status
+	kindDuration                  // Time since an event: lastSeen
+	kindLabel                     // label.<key>, empty if the device lacks the label
+)
+
+// field is a device property a query can test
+type field struct {
+	name     string
+	kind     fieldKind
+	str      func(d *models.Device) string
+	boolean  func(d *models.Device) bool
+	since    func(d *models.Device, now time.Time) (time.Duration, bool) // False if the event never happened
+	has      func(d *models.Device) bool                                 // Whether a label is present
+	validate func(value string) error
+}
+
+// fields are the device properties by lower-case name
+var fields = map[string]*field{
+	"id":   {name: "id", kind: kindString, str: func(d *models.Device) string { return d.ID }},
+	"name": {name: "name", kind: kindString, str: func(d *models.Device) string { return d.Name }},
+	"group": {name: "group", kind: kindFold, str: func(d *models.Device) string {
+		if d.GroupName == nil {
+			return ""
+		}
+		return *d.GroupName
+	}},
+	"status":  {name: "status", kind: kindFold, str: func(d *models.Device) string { return string(d.Status) }, validate: validateStatus},
+	"online":  {name: "online", kind: kindBool, boolean: func(d *models.Device) bool { return d.Online }},
+	"offline": {name: "offline", kind: kindBool, boolean: func(d *models.Device) bool { return !d.Online }},
+	"lastseen": {name: "lastSeen", kind: kindDuration, since: func(d *models.Device, now time.Time) (time.Duration, bool) {
+		if d.LastHeartbeat == 0 {
+			return 0, false
+		}
+		return now.Sub(time.UnixMilli(d.LastHeartbeat)), true
+	}},
+}
+
+// labelPrefixes introduce a label field, e.g. label.site
+var labelPrefixes = []string{"label.", "labels."}
+
+// lookupField returns the field with the given name, case-insensitively
+// except for label keys
+func lookupField(name string) (*field, bool) {
+	for _, prefix := range labelPrefixes {
+		if len(name) > len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+			return labelField(name[len(prefix):]), true
+		}
+	}
+	f, ok := fields[strings.ToLower(name)]
+	return f, ok
+}
+
+// labelField is the field for the label with the given key
+func labelField(key string) *field {
+	return &field{
+		name: "label." + key,
+		kind: kindLabel,
+		str:  func(d *models.Device) string { return d.Labels[key] },
+		has: func(d *models.Device) bool {
+			_, ok := d.Labels[key]
+			return ok
+		},
+	}
+}
+
+// FieldNames lists the fields a query can test
+func FieldNames() []string {
+	names := make([]string, 0, len(fields)+1)
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	slices.Sort(names)
+	return append(names, "label.<key>")
+}
+
+// validateStatus rejects approval statuses that do not exist
+func validateStatus(value string) error {
+	switch models.DeviceStatus(strings.ToUpper(value)) {
+	case models.DeviceStatusPending, models.DeviceStatusApproved, models.DeviceStatusRejected, models.DeviceStatusDecommissioned:
+		return nil
+	}
+	return fmt.Errorf("unknown status %q: must be PENDING, APPROVED, REJECTED or DECOMMISSIONED", value)
+}
+
+// condition compares a field with one or more values
+type condition struct {
+	field    *field
+	op       string // =, !=, ~, !~, <, <=, >, >=, in, not in; empty for a bare boolean field
+	values   []string
+	re       *regexp.Regexp
+	duration time.Duration
+}
+
+func (c *condition) match(d *models.Device, now time.Time) bool {
+	switch c.field.kind {
+	case kindBool:
+		return c.matchBool(c.field.boolean(d))
+	case kindLabel:
+		if c.op == "" {
+			return c.field.has(d)
+		}
+	case kindDuration:
+		since, ok := c.field.since(d, now)
+		if !ok {
+			// Never is longer ago than any duration
+			return c.op == ">" || c.op == ">="
+		}
+		switch c.op {
+		case "<":
+			return since < c.duration
+		case "<=":
+			return since <= c.duration
+		case ">":
+			return since > c.duration
+		default:
+			return since >= c.duration
+		}
+	}
+
+	value := c.field.str(d)
+	switch c.op {
+	case "~":
+		return c.re.MatchString(value)
+	case "!~":
+		return !c.re.MatchString(value)
+	case "!=", "not in":
+		return !c.contains(value)
+	default:
+		return c.contains(value)
+	}
+}
+
+// matchBool applies the condition to the value of a boolean field
+func (c *condition) matchBool(value bool) bool {
+	if c.op == "" {
+		return value
+	}
+	want := c.values[0] == "true"
+	if c.op == "!=" {
+		want = !want
+	}
+	return value == want
+}
+
+// contains reports whether a value is one of the condition's values
+func (c *condition) contains(value string) bool {
+	for _, v := range c.values {
+		if v == value || (c.field.kind == kindFold && strings.EqualFold(v, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// operators lists the operators each kind of field accepts
+var operators = map[fieldKind][]string{
+	kindBool:     {"=", "!="},
+	kindString:   {"=", "!=", "~", "!~", "in", "not in"},
+	kindFold:     {"=", "!=", "~", "!~", "in", "not in"},
+	kindDuration: {"<", "<=", ">", ">="},
+	kindLabel:    {"=", "!=", "~", "!~", "in", "not in"},
+}
+
+// newCondition checks and compiles a condition
+func newCondition(f *field, op string, values []string, pos int) (*condition, error) {
+	c := &condition{field: f, op: op, values: values}
+	if op == "" {
+		if f.kind != kindBool && f.kind != kindLabel {
+			return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("%s needs a comparison, e.g. %s=value", f.name, f.name)}
+		}
+		return c, nil
+	}
+	if op == "==" {
+		c.op = "="
+	}
+	if !slices.Contains(operators[f.kind], c.op) {
+		return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("%s does not support %s", f.name, op)}
+	}
+
+	switch {
+	case f.kind == kindBool:
+		if values[0] != "true" && values[0] != "false" {
+			return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("%s must be compared with true or false", f.name)}
+		}
+	case f.kind == kindDuration:
+		d, err := ParseDuration(values[0])
+		if err != nil {
+			return nil, &SyntaxError{Pos: pos, Msg: err.Error()}
+		}
+		c.duration = d
+	case c.op == "~" || c.op == "!~":
+		re, err := regexp.Compile(values[0])
+		if err != nil {
+			return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+		c.re = re
+	case f.validate != nil:
+		for _, v := range values {
+			if err := f.validate(v); err != nil {
+				return nil, &SyntaxError{Pos: pos, Msg: err.Error()}
+			}
+		}
+	}
+	return c, nil
+}
+
+// ParseDuration parses a duration like time.ParseDuration, additionally
+// accepting days (d) and weeks (w), e.g. 2h, 90m, 7d or 1w2d
+func ParseDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			break
+		}
+		unit := time.Duration(0)
+		switch rest[i] {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		}
+		if unit == 0 {
+			break
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+	if rest == "" {
+		if s == "" {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return total, nil
+	}
+	d, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, e.g. 30m, 2h or 7d", s)
+	}
+	return total + d, nil
+}
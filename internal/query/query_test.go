@@ -0,0 +1,291 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+var now = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+// testDevice creates a device last seen the given time ago
+func testDevice(name, group string, online bool, status models.DeviceStatus, lastSeen time.Duration) models.Device {
+	d := models.Device{ID: "id-" + name, Name: name, Online: online, Status: status}
+	if group != "" {
+		d.GroupName = &group
+	}
+	if lastSeen > 0 {
+		d.LastHeartbeat = now.Add(-lastSeen).UnixMilli()
+	}
+	return d
+}
+
+// withLabels sets the labels of a device
+func withLabels(d models.Device, labels map[string]string) models.Device {
+	d.Labels = labels
+	return d
+}
+
+var fleet = []models.Device{
+	withLabels(testDevice("press-01", "line-1", true, models.DeviceStatusApproved, time.Minute), map[string]string{"site": "hamburg", "hw": "rev3"}),
+	withLabels(testDevice("press-02", "line-2", false, models.DeviceStatusApproved, 3*time.Hour), map[string]string{"site": "hamburg", "hw": "rev1", "customer": "Åhus"}),
+	withLabels(testDevice("lathe-01", "Line-1", false, models.DeviceStatusApproved, 30*time.Minute), map[string]string{"site": "Bremen", "hw": ""}),
+	testDevice("gateway-01", "", true, models.DeviceStatusPending, time.Minute),
+	testDevice("mill-01", "line-3", false, models.DeviceStatusDecommissioned, 0),
+}
+
+func TestParse_Match(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "online", want: []string{"press-01", "gateway-01"}},
+		{query: "offline", want: []string{"press-02", "lathe-01", "mill-01"}},
+		{query: "!online", want: []string{"press-02", "lathe-01", "mill-01"}},
+		{query: "online=false", want: []string{"press-02", "lathe-01", "mill-01"}},
+		{query: "status=approved", want: []string{"press-01", "press-02", "lathe-01"}},
+		{query: "status != APPROVED", want: []string{"gateway-01", "mill-01"}},
+		{query: "group=line-1", want: []string{"press-01", "lathe-01"}},
+		{query: `group=""`, want: []string{"gateway-01"}},
+		{query: "group in (line-2, line-3)", want: []string{"press-02", "mill-01"}},
+		{query: "group not in (line-1,line-2)", want: []string{"gateway-01", "mill-01"}},
+		{query: `name~"^press-"`, want: []string{"press-01", "press-02"}},
+		{query: `name!~"-01$"`, want: []string{"press-02"}},
+		{query: "name=press-01", want: []string{"press-01"}},
+		{query: "id==id-lathe-01", want: []string{"lathe-01"}},
+		{query: "lastSeen>2h", want: []string{"press-02", "mill-01"}},
+		{query: "lastseen<=30m", want: []string{"press-01", "lathe-01", "gateway-01"}},
+		{query: "lastSeen<1d", want: []string{"press-01", "press-02", "lathe-01", "gateway-01"}},
+		{query: "online || group=line-2 && lastSeen>2h", want: []string{"press-01", "press-02", "gateway-01"}},
+		{query: "(online || group=line-2) && status=APPROVED", want: []string{"press-01", "press-02"}},
+		{query: "label.site=hamburg", want: []string{"press-01", "press-02"}},
+		{query: "label.site", want: []string{"press-01", "press-02", "lathe-01"}},
+		{query: "!labels.hw", want: []string{"gateway-01", "mill-01"}},
+		{query: `label.hw=""`, want: []string{"lathe-01", "gateway-01", "mill-01"}},
+		{query: "label.site!=hamburg", want: []string{"lathe-01", "gateway-01", "mill-01"}},
+		{query: "label.site=bremen", want: nil},
+		{query: `label.hw~"^rev[23]$"`, want: []string{"press-01"}},
+		{query: "Label.hw in (rev1, rev3) && online", want: []string{"press-01"}},
+		{query: "label.customer=Åhus", want: []string{"press-02"}},
+		{query: "label.customer=Åhus\f&&\voffline", want: []string{"press-02"}},
+		{query: "group=Sàrl", want: nil},
+		{query: "\u00a0online\u0085", want: []string{"press-01", "gateway-01"}},
+		{
+			query: `online && status=APPROVED && group in (line-1,line-2) && name~"^press-" && lastSeen<2h`,
+			want:  []string{"press-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.query, err)
+			}
+			var got []string
+			for i := range fleet {
+				if expr.Match(&fleet[i], now) {
+					got = append(got, fleet[i].Name)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("matched %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{query: "", pos: 1},
+		{query: "color=red", pos: 1},
+		{query: "name", pos: 1},
+		{query: "online &", pos: 8},
+		{query: "online && (group=a", pos: 19},
+		{query: "status=ACTIVE", pos: 1},
+		{query: "lastSeen>soon", pos: 1},
+		{query: "lastSeen=2h", pos: 1},
+		{query: `name~"["`, pos: 1},
+		{query: `name="press`, pos: 6},
+		{query: "group in line-1", pos: 10},
+		{query: "online=yes", pos: 1},
+		{query: "online offline", pos: 8},
+		{query: "label.", pos: 1},
+		{query: "label.site<2", pos: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) error = %v, want a syntax error", tt.query, err)
+			}
+			if syntaxErr.Pos+1 != tt.pos {
+				t.Errorf("Parse(%q) error at position %d, want %d: %v", tt.query, syntaxErr.Pos+1, tt.pos, err)
+			}
+		})
+	}
+}
+
+func TestExpr_ServerFilters(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus string
+		wantGroup  string
+	}{
+		{query: "online", wantStatus: "online"},
+		{query: "!online && group=line-1", wantStatus: "offline", wantGroup: "line-1"},
+		{query: "offline", wantStatus: "offline"},
+		{query: "online=false", wantStatus: "offline"},
+		{query: "online || group=line-1"},
+		{query: "group!=line-1"},
+		{query: "group in (line-1)"},
+		{query: "name~press && (lastSeen>1h && group=line-2)", wantGroup: "line-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.query, err)
+			}
+			status, group := expr.ServerFilters()
+			if status != tt.wantStatus || group != tt.wantGroup {
+				t.Errorf("ServerFilters() = %q, %q; want %q, %q", status, group, tt.wantStatus, tt.wantGroup)
+			}
+		})
+	}
+}
+
+func TestExpr_Tests(t *testing.T) {
+	expr, err := Parse("online && !(group=line-1 || Status=PENDING)")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	for field, want := range map[string]bool{"online": true, "group": true, "status": true, "name": false} {
+		if got := expr.Tests(field); got != want {
+			t.Errorf("Tests(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+		wantErr  bool
+	}{
+		{selector: "site=hamburg", want: []string{"press-01", "press-02"}},
+		{selector: "site==hamburg,hw!=rev1", want: []string{"press-01"}},
+		{selector: "site", want: []string{"press-01", "press-02", "lathe-01"}},
+		{selector: "!hw", want: []string{"gateway-01", "mill-01"}},
+		{selector: "hw=", want: []string{"lathe-01"}},
+		{selector: "hw in (rev1,rev3)", want: []string{"press-01", "press-02"}},
+		{selector: "hw notin (rev1), site", want: []string{"press-01", "lathe-01"}},
+		{selector: "customer=Åhus", want: []string{"press-02"}},
+		{selector: "customer in (Sàrl),\fsite", want: nil},
+		{selector: "", wantErr: true},
+		{selector: "site=hamburg hw=rev3", wantErr: true},
+		{selector: "site=hamburg,", wantErr: true},
+		{selector: "hw in rev1", wantErr: true},
+		{selector: "site~ham", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			expr, err := ParseSelector(tt.selector)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSelector(%q) expected an error", tt.selector)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSelector(%q) unexpected error: %v", tt.selector, err)
+			}
+			var got []string
+			for i := range fleet {
+				if expr.Match(&fleet[i], now) {
+					got = append(got, fleet[i].Name)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "2h", want: 2 * time.Hour},
+		{in: "90m", want: 90 * time.Minute},
+		{in: "7d", want: 7 * 24 * time.Hour},
+		{in: "1w2d", want: 9 * 24 * time.Hour},
+		{in: "1d12h", want: 36 * time.Hour},
+		{in: "", wantErr: true},
+		{in: "5", wantErr: true},
+		{in: "d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDuration(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		key     string
+		reverse bool
+		want    []string
+	}{
+		{key: "name", want: []string{"gateway-01", "lathe-01", "mill-01", "press-01", "press-02"}},
+		{key: "name", reverse: true, want: []string{"press-02", "press-01", "mill-01", "lathe-01", "gateway-01"}},
+		{key: "group", want: []string{"gateway-01", "lathe-01", "press-01", "press-02", "mill-01"}},
+		{key: "online", want: []string{"gateway-01", "press-01", "lathe-01", "mill-01", "press-02"}},
+		{key: "lastSeen", want: []string{"mill-01", "press-02", "lathe-01", "gateway-01", "press-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			devices := append([]models.Device(nil), fleet...)
+			if err := Sort(devices, tt.key, tt.reverse); err != nil {
+				t.Fatalf("Sort(%q) unexpected error: %v", tt.key, err)
+			}
+			for i, d := range devices {
+				if d.Name != tt.want[i] {
+					var got []string
+					for _, d := range devices {
+						got = append(got, d.Name)
+					}
+					t.Fatalf("Sort(%q) = %q, want %q", tt.key, got, tt.want)
+				}
+			}
+		})
+	}
+
+	if err := Sort(fleet, "color", false); err == nil {
+		t.Error("Sort(color) expected an error")
+	}
+}
@@ -0,0 +1,101 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ParseSelector parses a label selector, a comma-separated list of label
+// requirements that all have to hold:
+//
+//	site=hamburg    the label has the value (also ==)
+//	hw!=rev1        the label is missing or has another value
+//	hw=             the label is present with an empty value
+//	site            the label is present
+//	!site           the label is missing
+//	hw in (rev2,rev3)
+//	hw notin (rev1)
+func ParseSelector(s string) (*Expr, error) {
+	expr, err := parseSelector(s)
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		syntaxErr.selector = true
+	}
+	return expr, err
+}
+
+func parseSelector(s string) (*Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var root node
+	for {
+		n, err := p.parseRequirement()
+		if err != nil {
+			return nil, err
+		}
+		if root == nil {
+			root = n
+		} else {
+			root = &andNode{left: root, right: n}
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q, separate requirements with \",\"", t.text)}
+	}
+	return &Expr{src: s, root: root}, nil
+}
+
+// parseRequirement parses one requirement of a label selector
+func (p *parser) parseRequirement() (node, error) {
+	negated := p.accept("!")
+	key := p.next()
+	if key.kind != tokWord && key.kind != tokString {
+		return nil, &SyntaxError{Pos: key.pos, Msg: fmt.Sprintf("expected a label key, found %q", key.text)}
+	}
+	f := labelField(key.text)
+	if negated {
+		return &notNode{operand: &condition{field: f}}, nil
+	}
+
+	present := &condition{field: f}
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && (t.text == "=" || t.text == "==" || t.text == "!="):
+		p.next()
+		// An empty value is written as nothing, e.g. site=
+		value := ""
+		if v := p.peek(); v.kind == tokWord || v.kind == tokString {
+			value = p.next().text
+		}
+		c, err := newCondition(f, t.text, []string{value}, key.pos)
+		if err != nil {
+			return nil, err
+		}
+		if c.op == "!=" {
+			return c, nil
+		}
+		return &andNode{left: present, right: c}, nil
+	case t.kind == tokWord && (t.text == "in" || t.text == "notin"):
+		p.next()
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if t.text == "notin" {
+			return newCondition(f, "not in", values, key.pos)
+		}
+		c, err := newCondition(f, "in", values, key.pos)
+		if err != nil {
+			return nil, err
+		}
+		return &andNode{left: present, right: c}, nil
+	default:
+		return present, nil
+	}
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// sortKeys compare two devices by a field; ties are broken by name
+var sortKeys = map[string]func(a, b *models.Device) int{
+	"name":   func(a, b *models.Device) int { return strings.Compare(a.Name, b.Name) },
+	"id":     func(a, b *models.Device) int { return strings.Compare(a.ID, b.ID) },
+	"status": func(a, b *models.Device) int { return strings.Compare(string(a.Status), string(b.Status)) },
+	"group": func(a, b *models.Device) int {
+		return strings.Compare(strings.ToLower(fields["group"].str(a)), strings.ToLower(fields["group"].str(b)))
+	},
+	// Online devices first
+	"online": func(a, b *models.Device) int {
+		switch {
+		case a.Online == b.Online:
+			return 0
+		case a.Online:
+			return -1
+		default:
+			return 1
+		}
+	},
+	// The device seen longest ago first
+	"lastseen": func(a, b *models.Device) int {
+		switch {
+		case a.LastHeartbeat < b.LastHeartbeat:
+			return -1
+		case a.LastHeartbeat > b.LastHeartbeat:
+			return 1
+		default:
+			return 0
+		}
+	},
+}
+
+// SortKeys lists the fields devices can be sorted by
+var SortKeys = []string{"name", "id", "status", "online", "group", "lastSeen"}
+
+// CheckSortKey returns an error if devices cannot be sorted by a field
+func CheckSortKey(key string) error {
+	if _, ok := sortKeys[strings.ToLower(key)]; !ok {
+		return fmt.Errorf("cannot sort by %q, use one of %s", key, strings.Join(SortKeys, ", "))
+	}
+	return nil
+}
+
+// Sort orders devices by a field in place, in reverse if requested
+func Sort(devices []models.Device, key string, reverse bool) error {
+	if err := CheckSortKey(key); err != nil {
+		return err
+	}
+	compare := sortKeys[strings.ToLower(key)]
+	sort.SliceStable(devices, func(i, j int) bool {
+		a, b := &devices[i], &devices[j]
+		if reverse {
+			a, b = b, a
+		}
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+		return a.Name < b.Name
+	})
+	return nil
+}
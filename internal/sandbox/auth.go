@@ -0,0 +1,395 @@
+package sandbox
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+)
+
+// tokenLifetime is the lifetime of issued ID and access tokens
+const tokenLifetime = time.Hour
+
+// loginSessionLifetime is how long a browser login can be completed
+const loginSessionLifetime = 10 * time.Minute
+
+// loginSession is a pending browser login started by 'iot auth login'
+type loginSession struct {
+	id        string
+	userCode  string
+	expiresAt time.Time
+	tokens    *tokenSet
+	info      api.Session
+}
+
+// cliSession is a completed login, listed by 'iot auth sessions'
+type cliSession struct {
+	info         api.Session
+	refreshToken string
+}
+
+// tokenSet is a set of tokens issued for a login
+type tokenSet struct {
+	AccessToken  string
+	IDToken      string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+// issueTokens signs new ID and access tokens. A new refresh token is issued
+// unless one is given. The caller must hold s.mu.
+func (s *Server) issueTokens(refreshToken string) (*tokenSet, error) {
+	now := time.Now()
+	common := map[string]interface{}{
+		"sub":       userID,
+		"iss":       s.url,
+		"iat":       now.Unix(),
+		"exp":       now.Add(tokenLifetime).Unix(),
+		"auth_time": now.Unix(),
+	}
+
+	id := map[string]interface{}{
+		"token_use":        "id",
+		"aud":              ClientID,
+		"email":            UserEmail,
+		"cognito:username": userID,
+		"cognito:groups":   []string{"admin"},
+		"custom:tenant_id": TenantID,
+	}
+	access := map[string]interface{}{
+		"token_use": "access",
+		"client_id": ClientID,
+		"username":  userID,
+		"jti":       randomID(8),
+	}
+	for k, v := range common {
+		id[k] = v
+		access[k] = v
+	}
+
+	idToken, err := s.sign(id)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.sign(access)
+	if err != nil {
+		return nil, err
+	}
+
+	if refreshToken == "" {
+		refreshToken = randomID(32)
+	}
+	s.accessTokens[accessToken] = true
+
+	return &tokenSet{
+		AccessToken:  accessToken,
+		IDToken:      idToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(tokenLifetime.Seconds()),
+	}, nil
+}
+
+// sign creates an RS256 JWT with the sandbox's signing key
+func (s *Server) sign(claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.keyID})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// handleJWKS serves the public signing key
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": s.keyID,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+// handleCognito implements the user pool API calls of the CLI: password login,
+// token refresh, token revocation and global sign-out. Any password is
+// accepted.
+func (s *Server) handleCognito(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		AuthFlow       string
+		AuthParameters map[string]string
+		Token          string
+		AccessToken    string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeCognitoError(w, "InvalidParameterException", "invalid request")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := r.Header.Get("X-Amz-Target")
+	switch target[strings.LastIndex(target, ".")+1:] {
+	case "InitiateAuth":
+		refreshToken := ""
+		switch body.AuthFlow {
+		case "USER_PASSWORD_AUTH":
+			if body.AuthParameters["USERNAME"] == "" || body.AuthParameters["PASSWORD"] == "" {
+				writeCognitoError(w, "NotAuthorizedException", "Incorrect username or password.")
+				return
+			}
+		case "REFRESH_TOKEN_AUTH", "REFRESH_TOKEN":
+			refreshToken = body.AuthParameters["REFRESH_TOKEN"]
+			if _, ok := s.refreshTokens[refreshToken]; !ok {
+				writeCognitoError(w, "NotAuthorizedException", "Refresh Token has been revoked")
+				return
+			}
+		default:
+			writeCognitoError(w, "InvalidParameterException", "unsupported auth flow "+body.AuthFlow)
+			return
+		}
+
+		tokens, err := s.issueTokens(refreshToken)
+		if err != nil {
+			writeCognitoError(w, "InternalErrorException", err.Error())
+			return
+		}
+		result := map[string]interface{}{
+			"AccessToken": tokens.AccessToken,
+			"IdToken":     tokens.IDToken,
+			"ExpiresIn":   tokens.ExpiresIn,
+			"TokenType":   "Bearer",
+		}
+		if refreshToken == "" {
+			s.refreshTokens[tokens.RefreshToken] = ""
+			result["RefreshToken"] = tokens.RefreshToken
+		}
+		writeCognitoJSON(w, map[string]interface{}{"AuthenticationResult": result})
+
+	case "RevokeToken":
+		s.revokeRefreshToken(body.Token)
+		writeCognitoJSON(w, map[string]interface{}{})
+
+	case "GlobalSignOut":
+		if !s.accessTokens[body.AccessToken] {
+			writeCognitoError(w, "NotAuthorizedException", "Access Token has been revoked")
+			return
+		}
+		s.accessTokens = make(map[string]bool)
+		s.refreshTokens = make(map[string]string)
+		s.cliSessions = make(map[string]*cliSession)
+		writeCognitoJSON(w, map[string]interface{}{})
+
+	default:
+		writeCognitoError(w, "InvalidActionException", "unsupported action "+target)
+	}
+}
+
+// revokeRefreshToken invalidates a refresh token and ends its CLI session.
+// The caller must hold s.mu.
+func (s *Server) revokeRefreshToken(token string) {
+	if sessionID, ok := s.refreshTokens[token]; ok {
+		delete(s.cliSessions, sessionID)
+		delete(s.refreshTokens, token)
+	}
+}
+
+// handleCreateLoginSession starts a browser login
+func (s *Server) handleCreateLoginSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DeviceName string `json:"deviceName"`
+		Platform   string `json:"platform"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	session := &loginSession{
+		id:        randomID(16),
+		userCode:  strings.ToUpper(randomID(2) + "-" + randomID(2)),
+		expiresAt: time.Now().Add(loginSessionLifetime),
+		info: api.Session{
+			DeviceName: req.DeviceName,
+			Platform:   req.Platform,
+			IPAddress:  remoteIP(r),
+		},
+	}
+
+	s.mu.Lock()
+	s.loginSessions[session.id] = session
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, map[string]string{
+		"sessionId": session.id,
+		"loginUrl":  s.url + "/login?session=" + session.id,
+		"userCode":  session.userCode,
+		"expiresAt": session.expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// handleLoginPage completes a browser login, standing in for the platform's
+// login page
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	session, ok := s.loginSessions[r.URL.Query().Get("session")]
+	var err error
+	if ok {
+		err = s.completeLogin(session)
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	switch {
+	case !ok:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "<h1>Unknown or expired login</h1>")
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "<h1>Login failed</h1><p>%s</p>", html.EscapeString(err.Error()))
+	default:
+		fmt.Fprintf(w, "<h1>Signed in to the IoT sandbox</h1><p>Code: %s. You can close this window and return to the terminal.</p>",
+			html.EscapeString(session.userCode))
+	}
+}
+
+// completeLogin issues the tokens of a browser login. The caller must hold s.mu.
+func (s *Server) completeLogin(session *loginSession) error {
+	if session.tokens != nil {
+		return nil
+	}
+
+	tokens, err := s.issueTokens("")
+	if err != nil {
+		return err
+	}
+	session.tokens = tokens
+
+	info := session.info
+	info.ID = randomID(8)
+	info.CreatedAt = time.Now().UTC()
+	s.cliSessions[info.ID] = &cliSession{info: info, refreshToken: tokens.RefreshToken}
+	s.refreshTokens[tokens.RefreshToken] = info.ID
+	return nil
+}
+
+// handleLoginSessionStatus reports whether a browser login has completed
+func (s *Server) handleLoginSessionStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.loginSessions[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "", "session not found")
+		return
+	}
+
+	if time.Now().After(session.expiresAt) && session.tokens == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "expired"})
+		return
+	}
+	if s.opts.AutoLogin {
+		if err := s.completeLogin(session); err != nil {
+			writeError(w, http.StatusInternalServerError, "", err.Error())
+			return
+		}
+	}
+	if session.tokens == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "pending"})
+		return
+	}
+
+	// Tokens are handed out once
+	delete(s.loginSessions, session.id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":       "completed",
+		"accessToken":  session.tokens.AccessToken,
+		"idToken":      session.tokens.IDToken,
+		"refreshToken": session.tokens.RefreshToken,
+		"expiresIn":    session.tokens.ExpiresIn,
+	})
+}
+
+// handleListCLISessions lists the completed logins
+func (s *Server) handleListCLISessions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := []api.Session{}
+	for _, session := range s.cliSessions {
+		sessions = append(sessions, session.info)
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// handleRevokeCLISession ends a login and invalidates its refresh token
+func (s *Server) handleRevokeCLISession(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.cliSessions[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "", "session not found")
+		return
+	}
+	s.revokeRefreshToken(session.refreshToken)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTenants lists the single sandbox tenant
+func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, []api.Tenant{{ID: TenantID, Name: TenantName, Role: "admin"}})
+}
+
+// remoteIP returns the client address of a request without the port
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeCognitoJSON writes a user pool API response
+func writeCognitoJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeCognitoError writes a user pool API error
+func writeCognitoError(w http.ResponseWriter, errorType, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Header().Set("X-Amzn-ErrorType", errorType)
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"__type": errorType, "message": message})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an API error response
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": message, "requestId": randomID(8)})
+}
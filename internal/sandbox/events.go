@@ -0,0 +1,70 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// keepAliveInterval is how often an idle event stream sends a comment, so
+// that proxies do not close it
+const keepAliveInterval = 15 * time.Second
+
+// publish sends a device's new state to the event stream subscribers. Slow
+// subscribers miss events rather than block the sandbox. The caller must
+// hold s.mu.
+func (s *Server) publish(d *device) {
+	for ch := range s.subscribers {
+		select {
+		case ch <- d.Device:
+		default:
+		}
+	}
+}
+
+// handleDeviceEvents streams device changes as server-sent events
+func (s *Server) handleDeviceEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "", "streaming not supported")
+		return
+	}
+
+	ch := make(chan models.Device, 64)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.stop:
+			return
+		case <-keepAlive.C:
+			_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+		case d := <-ch:
+			data, _ := json.Marshal(d)
+			if _, err := fmt.Fprintf(w, "event: device\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,186 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+)
+
+// maxUploadSize bounds the size of a single upload
+const maxUploadSize = 256 << 20
+
+// localPath maps an absolute device path into the device's root directory.
+// Paths cannot escape the root.
+func (d *device) localPath(devicePath string) string {
+	return filepath.Join(d.root, filepath.FromSlash(path.Clean("/"+devicePath)))
+}
+
+// fileInfo describes a local file with its device path
+func fileInfo(devicePath string, info fs.FileInfo) api.FileInfo {
+	return api.FileInfo{
+		Name:        info.Name(),
+		Path:        devicePath,
+		Size:        info.Size(),
+		IsDirectory: info.IsDir(),
+		Mode:        info.Mode().String(),
+		ModTime:     info.ModTime().Unix(),
+	}
+}
+
+// writeFileError maps a file system error to an API error
+func writeFileError(w http.ResponseWriter, devicePath string, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("%s: no such file or directory", devicePath))
+	case errors.Is(err, fs.ErrExist):
+		writeError(w, http.StatusConflict, api.CodeAlreadyExists, fmt.Sprintf("%s: already exists", devicePath))
+	case errors.Is(err, fs.ErrPermission):
+		writeError(w, http.StatusForbidden, "", fmt.Sprintf("%s: permission denied", devicePath))
+	default:
+		writeError(w, http.StatusInternalServerError, "", err.Error())
+	}
+}
+
+// handleListFiles lists a directory on a device
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.onlineDevice(w, r)
+	if !ok {
+		return
+	}
+	devicePath := path.Clean("/" + r.URL.Query().Get("path"))
+
+	entries, err := os.ReadDir(d.localPath(devicePath))
+	if err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+
+	files := []api.FileInfo{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, fileInfo(path.Join(devicePath, entry.Name()), info))
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+// handleStatFile describes a file on a device
+func (s *Server) handleStatFile(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.onlineDevice(w, r)
+	if !ok {
+		return
+	}
+	devicePath := path.Clean("/" + r.URL.Query().Get("path"))
+
+	info, err := os.Stat(d.localPath(devicePath))
+	if err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fileInfo(devicePath, info))
+}
+
+// handleDownload sends the content of a file on a device
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.onlineDevice(w, r)
+	if !ok {
+		return
+	}
+	devicePath := path.Clean("/" + r.URL.Query().Get("path"))
+
+	f, err := os.Open(d.localPath(devicePath))
+	if err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	if info.IsDir() {
+		writeError(w, http.StatusBadRequest, "", fmt.Sprintf("%s is a directory", devicePath))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	n, _ := io.Copy(w, f)
+	s.addTransfer(n)
+}
+
+// handleUpload writes the uploaded file to a path on a device
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.onlineDevice(w, r)
+	if !ok {
+		return
+	}
+	devicePath := path.Clean("/" + r.URL.Query().Get("path"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	part, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", "missing file: "+err.Error())
+		return
+	}
+	defer part.Close()
+
+	localPath := d.localPath(devicePath)
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	f, err := os.Create(localPath)
+	if err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	n, err := io.Copy(f, part)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+
+	s.addTransfer(n)
+	info, err := os.Stat(localPath)
+	if err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, fileInfo(devicePath, info))
+}
+
+// handleMkdir creates a directory on a device
+func (s *Server) handleMkdir(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.onlineDevice(w, r)
+	if !ok {
+		return
+	}
+	devicePath := path.Clean("/" + r.URL.Query().Get("path"))
+
+	localPath := d.localPath(devicePath)
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	if err := os.Mkdir(localPath, 0755); err != nil {
+		writeFileError(w, devicePath, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
@@ -0,0 +1,397 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/file"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// defaultPageSize is the page size of device listings without one
+const defaultPageSize = 50
+
+// machineTypes name the simulated devices, e.g. press-01, lathe-01
+var machineTypes = []string{"press", "lathe", "mill", "robot", "conveyor"}
+
+// sites and hardwareRevisions are the labels of the simulated devices
+var (
+	sites             = []string{"hamburg", "bremen"}
+	hardwareRevisions = []string{"rev1", "rev2", "rev3"}
+)
+
+// device is a simulated device. Its file system is a directory in the
+// sandbox's data directory.
+type device struct {
+	models.Device
+	root string
+}
+
+// createFleet creates the simulated devices and their file systems
+func (s *Server) createFleet() error {
+	now := time.Now()
+	approvedAt := now.Add(-30 * 24 * time.Hour).UTC()
+	approvedBy := UserEmail
+
+	for i := 0; i < s.opts.Devices; i++ {
+		name := fmt.Sprintf("%s-%02d", machineTypes[i%len(machineTypes)], i/len(machineTypes)+1)
+		d := &device{
+			Device: models.Device{
+				ID:            fmt.Sprintf("dev-%04d", i+1),
+				TenantID:      TenantID,
+				Name:          name,
+				Online:        i < s.opts.Devices-s.opts.Offline,
+				LastHeartbeat: now.UnixMilli(),
+				Status:        models.DeviceStatusApproved,
+				ApprovedAt:    &approvedAt,
+				ApprovedBy:    &approvedBy,
+				Labels: map[string]string{
+					"site": sites[i%len(sites)],
+					"hw":   hardwareRevisions[i%len(hardwareRevisions)],
+				},
+			},
+			root: filepath.Join(s.dataDir, name),
+		}
+		if !d.Online {
+			d.LastHeartbeat = now.Add(-time.Duration(i+1) * time.Hour).UnixMilli()
+		}
+		if len(s.opts.Groups) > 0 {
+			group := s.opts.Groups[i%len(s.opts.Groups)]
+			groupID := "grp-" + group
+			d.GroupID, d.GroupName = &groupID, &group
+		}
+
+		if err := seedFileSystem(d); err != nil {
+			return err
+		}
+		s.devices = append(s.devices, d)
+	}
+
+	// New gateways that have registered but are not admitted yet
+	tokenID := factoryTokenID
+	for i := 0; i < s.opts.Pending; i++ {
+		name := fmt.Sprintf("gateway-%02d", i+1)
+		d := &device{
+			Device: models.Device{
+				ID:                  fmt.Sprintf("dev-%04d", s.opts.Devices+i+1),
+				TenantID:            TenantID,
+				Name:                name,
+				Online:              true,
+				LastHeartbeat:       now.UnixMilli(),
+				Status:              models.DeviceStatusPending,
+				RegistrationTokenID: &tokenID,
+			},
+			root: filepath.Join(s.dataDir, name),
+		}
+		if err := seedFileSystem(d); err != nil {
+			return err
+		}
+		s.devices = append(s.devices, d)
+	}
+	return nil
+}
+
+// seedFileSystem creates a device's root directory with a few example files.
+// Existing files in a persistent data directory are kept.
+func seedFileSystem(d *device) error {
+	files := map[string]string{
+		"etc/hostname":     d.Name + "\n",
+		"etc/app/app.yaml": "device: " + d.Name + "\nlog_level: info\n",
+		"var/log/app.log":  time.Now().UTC().Format(time.RFC3339) + " INFO " + d.Name + " started\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(d.root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create device %s: %w", d.Name, err)
+		}
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to create device %s: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// findDevice returns the device with the given ID. The caller must hold s.mu.
+func (s *Server) findDevice(id string) *device {
+	for _, d := range s.devices {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+// lookupDevice returns a copy of the device addressed by the request path,
+// writing a 404 if there is none
+func (s *Server) lookupDevice(w http.ResponseWriter, r *http.Request) (*device, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDevice(r.PathValue("id"))
+	if d == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("device %q not found", r.PathValue("id")))
+		return nil, false
+	}
+	copied := *d
+	return &copied, true
+}
+
+// onlineDevice is lookupDevice for operations that reach the device itself
+func (s *Server) onlineDevice(w http.ResponseWriter, r *http.Request) (*device, bool) {
+	d, ok := s.lookupDevice(w, r)
+	if ok && !d.Online {
+		writeError(w, http.StatusBadRequest, api.CodeDeviceOffline, fmt.Sprintf("device %q is offline", d.Name))
+		return nil, false
+	}
+	return d, ok
+}
+
+// handleListDevices lists devices with the filters and paging of the API
+func (s *Server) handleListDevices(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status, group, name := q.Get("status"), q.Get("group"), strings.ToLower(q.Get("name"))
+
+	pageSize := defaultPageSize
+	if v := q.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "", "invalid pageSize")
+			return
+		}
+		pageSize = n
+	}
+	offset := 0
+	if v := q.Get("pageToken"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "", "invalid pageToken")
+			return
+		}
+		offset = n
+	}
+
+	s.mu.Lock()
+	var matching []models.Device
+	for _, d := range s.devices {
+		if status == "online" && !d.Online || status == "offline" && d.Online {
+			continue
+		}
+		if group != "" && (d.GroupName == nil || !strings.EqualFold(*d.GroupName, group)) {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(d.Name), name) {
+			continue
+		}
+		matching = append(matching, d.Device)
+	}
+	s.mu.Unlock()
+
+	page := api.DevicePage{Devices: []models.Device{}}
+	if offset < len(matching) {
+		end := min(offset+pageSize, len(matching))
+		page.Devices = matching[offset:end]
+		if end < len(matching) {
+			page.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handlePendingDevices lists devices waiting for approval
+func (s *Server) handlePendingDevices(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := []models.Device{}
+	for _, d := range s.devices {
+		if d.Status == models.DeviceStatusPending {
+			pending = append(pending, d.Device)
+		}
+	}
+	writeJSON(w, http.StatusOK, pending)
+}
+
+// handleGetDevice returns a single device
+func (s *Server) handleGetDevice(w http.ResponseWriter, r *http.Request) {
+	if d, ok := s.lookupDevice(w, r); ok {
+		writeJSON(w, http.StatusOK, d.Device)
+	}
+}
+
+// handleUpdateDevice applies a merge patch to a device's labels; a null
+// value removes a label
+func (s *Server) handleUpdateDevice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Labels map[string]*string `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "invalid request body")
+		return
+	}
+	for key, value := range req.Labels {
+		if err := api.ValidateLabelKey(key); err != nil {
+			writeError(w, http.StatusBadRequest, "", err.Error())
+			return
+		}
+		if value != nil {
+			if err := api.ValidateLabelValue(*value); err != nil {
+				writeError(w, http.StatusBadRequest, "", fmt.Sprintf("invalid value for label %s: %v", key, err))
+				return
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDevice(r.PathValue("id"))
+	if d == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("device %q not found", r.PathValue("id")))
+		return
+	}
+
+	// Replace the map rather than changing it, copies of the device share it
+	labels := maps.Clone(d.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for key, value := range req.Labels {
+		if value == nil {
+			delete(labels, key)
+		} else {
+			labels[key] = *value
+		}
+	}
+	d.Labels = labels
+	s.publish(d)
+	writeJSON(w, http.StatusOK, d.Device)
+}
+
+// changeStatus moves a device to a new approval status if it is in one of
+// the allowed states, and returns a copy of the result
+func (s *Server) changeStatus(w http.ResponseWriter, r *http.Request, to models.DeviceStatus, from ...models.DeviceStatus) (*models.Device, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDevice(r.PathValue("id"))
+	if d == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("device %q not found", r.PathValue("id")))
+		return nil, false
+	}
+	if !slices.Contains(from, d.Status) {
+		writeError(w, http.StatusConflict, "", fmt.Sprintf("device %q is %s", d.Name, d.Status))
+		return nil, false
+	}
+
+	now := time.Now().UTC()
+	by := actor(r)
+	d.Status = to
+	switch to {
+	case models.DeviceStatusApproved:
+		d.ApprovedAt, d.ApprovedBy = &now, &by
+	case models.DeviceStatusRejected:
+		d.RejectedAt, d.RejectedBy = &now, &by
+	case models.DeviceStatusDecommissioned:
+		d.DecommissionedAt = &now
+		d.Online = false
+	}
+	s.publish(d)
+	copied := d.Device
+	return &copied, true
+}
+
+// handleApproveDevice admits a pending device
+func (s *Server) handleApproveDevice(w http.ResponseWriter, r *http.Request) {
+	if d, ok := s.changeStatus(w, r, models.DeviceStatusApproved, models.DeviceStatusPending); ok {
+		writeJSON(w, http.StatusOK, d)
+	}
+}
+
+// handleRejectDevice refuses a pending device
+func (s *Server) handleRejectDevice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "", "invalid request body")
+			return
+		}
+	}
+
+	d, ok := s.changeStatus(w, r, models.DeviceStatusRejected, models.DeviceStatusPending)
+	if !ok {
+		return
+	}
+	if req.Reason != "" {
+		s.mu.Lock()
+		s.findDevice(d.ID).RejectionReason = &req.Reason
+		s.mu.Unlock()
+		d.RejectionReason = &req.Reason
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// handleDecommissionDevice retires a device
+func (s *Server) handleDecommissionDevice(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.changeStatus(w, r, models.DeviceStatusDecommissioned,
+		models.DeviceStatusPending, models.DeviceStatusApproved, models.DeviceStatusRejected)
+	if ok {
+		writeJSON(w, http.StatusOK, d)
+	}
+}
+
+// actor names who made a request: the user of a login or the API key
+func actor(r *http.Request) string {
+	if r.Header.Get("X-API-Key") != "" {
+		return "api-key"
+	}
+	return UserEmail
+}
+
+// addTransfer records bytes moved to or from devices for the usage report
+func (s *Server) addTransfer(n int64) {
+	s.mu.Lock()
+	s.bytesTransfer += n
+	s.mu.Unlock()
+}
+
+// usage returns the usage of the current month
+func (s *Server) usage() api.UsageData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return api.UsageData{
+		YearMonth:                 time.Now().UTC().Format("2006-01"),
+		BytesTransferred:          s.bytesTransfer,
+		BytesTransferredFormatted: file.FormatBytes(s.bytesTransfer),
+	}
+}
+
+// handleUsage reports the data transferred this month
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.usage())
+}
+
+// handleUsageHistory reports the monthly usage; the sandbox only knows the
+// current month
+func (s *Server) handleUsageHistory(w http.ResponseWriter, r *http.Request) {
+	u := s.usage()
+	writeJSON(w, http.StatusOK, []api.UsageHistoryData{{
+		YearMonth:                 u.YearMonth,
+		BytesTransferred:          u.BytesTransferred,
+		BytesTransferredFormatted: u.BytesTransferredFormatted,
+	}})
+}
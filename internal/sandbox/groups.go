@@ -0,0 +1,207 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+)
+
+// createGroups creates the groups the fleet is spread over
+func (s *Server) createGroups() {
+	createdAt := time.Now().Add(-30 * 24 * time.Hour).UTC()
+	for _, name := range s.opts.Groups {
+		s.groups = append(s.groups, &api.Group{ID: "grp-" + name, Name: name, CreatedAt: &createdAt})
+	}
+}
+
+// findGroup returns the group with the given ID. The caller must hold s.mu.
+func (s *Server) findGroup(id string) *api.Group {
+	for _, g := range s.groups {
+		if g.ID == id {
+			return g
+		}
+	}
+	return nil
+}
+
+// groupWithCounts returns a copy of a group with its member counts. The
+// caller must hold s.mu.
+func (s *Server) groupWithCounts(g *api.Group) api.Group {
+	counted := *g
+	for _, d := range s.devices {
+		if d.GroupID != nil && *d.GroupID == g.ID {
+			counted.DeviceCount++
+			if d.Online {
+				counted.OnlineCount++
+			}
+		}
+	}
+	return counted
+}
+
+// nameTaken reports whether another group already has a name. The caller
+// must hold s.mu.
+func (s *Server) nameTaken(name, exceptID string) bool {
+	for _, g := range s.groups {
+		if g.ID != exceptID && strings.EqualFold(g.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleListGroups lists the groups with their member counts
+func (s *Server) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := make([]api.Group, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, s.groupWithCounts(g))
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// handleGetGroup returns a single group
+func (s *Server) handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.findGroup(r.PathValue("id"))
+	if g == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("group %q not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.groupWithCounts(g))
+}
+
+// handleCreateGroup creates a group
+func (s *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		writeError(w, http.StatusBadRequest, "", "a group name is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nameTaken(req.Name, "") {
+		writeError(w, http.StatusConflict, api.CodeAlreadyExists, fmt.Sprintf("group %q already exists", req.Name))
+		return
+	}
+	now := time.Now().UTC()
+	g := &api.Group{ID: "grp-" + randomID(4), Name: req.Name, Description: req.Description, CreatedAt: &now}
+	s.groups = append(s.groups, g)
+	writeJSON(w, http.StatusCreated, g)
+}
+
+// handleRenameGroup changes a group's name, and with it the group name of
+// its devices
+func (s *Server) handleRenameGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		writeError(w, http.StatusBadRequest, "", "a group name is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.findGroup(r.PathValue("id"))
+	if g == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("group %q not found", r.PathValue("id")))
+		return
+	}
+	if s.nameTaken(req.Name, g.ID) {
+		writeError(w, http.StatusConflict, api.CodeAlreadyExists, fmt.Sprintf("group %q already exists", req.Name))
+		return
+	}
+
+	g.Name = req.Name
+	for _, d := range s.devices {
+		if d.GroupID != nil && *d.GroupID == g.ID {
+			name := g.Name
+			d.GroupName = &name
+			s.publish(d)
+		}
+	}
+	writeJSON(w, http.StatusOK, s.groupWithCounts(g))
+}
+
+// handleDeleteGroup deletes a group, leaving its devices without a group
+func (s *Server) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PathValue("id")
+	for i, g := range s.groups {
+		if g.ID != id {
+			continue
+		}
+		s.groups = append(s.groups[:i], s.groups[i+1:]...)
+		for _, d := range s.devices {
+			if d.GroupID != nil && *d.GroupID == id {
+				d.GroupID, d.GroupName = nil, nil
+				s.publish(d)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeError(w, http.StatusNotFound, "", fmt.Sprintf("group %q not found", id))
+}
+
+// handleAddGroupDevices moves devices into a group
+func (s *Server) handleAddGroupDevices(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DeviceIDs []string `json:"deviceIds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "", "invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.findGroup(r.PathValue("id"))
+	if g == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("group %q not found", r.PathValue("id")))
+		return
+	}
+	for _, id := range req.DeviceIDs {
+		if s.findDevice(id) == nil {
+			writeError(w, http.StatusNotFound, "", fmt.Sprintf("device %q not found", id))
+			return
+		}
+	}
+	for _, id := range req.DeviceIDs {
+		d := s.findDevice(id)
+		groupID, name := g.ID, g.Name
+		d.GroupID, d.GroupName = &groupID, &name
+		s.publish(d)
+	}
+	writeJSON(w, http.StatusOK, s.groupWithCounts(g))
+}
+
+// handleRemoveGroupDevice takes a device out of a group
+func (s *Server) handleRemoveGroupDevice(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDevice(r.PathValue("device"))
+	if d == nil || d.GroupID == nil || *d.GroupID != r.PathValue("id") {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("device %q is not in group %q", r.PathValue("device"), r.PathValue("id")))
+		return
+	}
+	d.GroupID, d.GroupName = nil, nil
+	s.publish(d)
+	w.WriteHeader(http.StatusNoContent)
+}
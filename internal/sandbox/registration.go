@@ -0,0 +1,114 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+)
+
+// factoryTokenID is the registration token the pending gateways used
+const factoryTokenID = "rt-factory"
+
+// createRegistrationTokens creates the token the pending gateways registered
+// with
+func (s *Server) createRegistrationTokens() {
+	createdAt := time.Now().Add(-7 * 24 * time.Hour).UTC()
+	s.tokens = append(s.tokens, &api.RegistrationToken{
+		ID:        factoryTokenID,
+		Token:     "rtk_" + randomID(16),
+		Uses:      s.opts.Pending,
+		CreatedAt: createdAt,
+		CreatedBy: UserEmail,
+	})
+}
+
+// findToken returns the registration token with the given ID. The caller
+// must hold s.mu.
+func (s *Server) findToken(id string) *api.RegistrationToken {
+	for _, t := range s.tokens {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// handleListRegistrationTokens lists the registration tokens without their
+// secrets
+func (s *Server) handleListRegistrationTokens(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := make([]api.RegistrationToken, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		listed := *t
+		listed.Token = ""
+		tokens = append(tokens, listed)
+	}
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// handleGetRegistrationToken returns a registration token with its secret
+func (s *Server) handleGetRegistrationToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.findToken(r.PathValue("id"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("registration token %q not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// handleCreateRegistrationToken creates a registration token
+func (s *Server) handleCreateRegistrationToken(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateRegistrationTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MaxUses < 0 {
+		writeError(w, http.StatusBadRequest, "", "invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := &api.RegistrationToken{
+		ID:        "rt-" + randomID(4),
+		Token:     "rtk_" + randomID(16),
+		MaxUses:   req.MaxUses,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: actor(r),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if req.GroupID != nil {
+		g := s.findGroup(*req.GroupID)
+		if g == nil {
+			writeError(w, http.StatusNotFound, "", fmt.Sprintf("group %q not found", *req.GroupID))
+			return
+		}
+		groupID, groupName := g.ID, g.Name
+		t.GroupID, t.GroupName = &groupID, &groupName
+	}
+	s.tokens = append(s.tokens, t)
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// handleRevokeRegistrationToken revokes a registration token
+func (s *Server) handleRevokeRegistrationToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.findToken(r.PathValue("id"))
+	if t == nil {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("registration token %q not found", r.PathValue("id")))
+		return
+	}
+	if t.RevokedAt == nil {
+		now := time.Now().UTC()
+		t.RevokedAt = &now
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
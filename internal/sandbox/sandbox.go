@@ -0,0 +1,304 @@
+// Package sandbox implements an in-process fake of the IoT platform for demos,
+// training and testing scripts without a real tenant. It serves the API
+// endpoints the CLI uses, a Cognito user pool for login and token refresh,
+// and simulates a fleet of devices backed by local directories and shells.
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/config"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// Identity of the simulated tenant, user and user pool client
+const (
+	TenantID   = "sandbox"
+	TenantName = "Sandbox"
+	UserEmail  = "demo@sandbox.local"
+	ClientID   = "sandbox-cli"
+	userID     = "sandbox-user"
+)
+
+// heartbeatInterval is how often online devices report in
+const heartbeatInterval = 10 * time.Second
+
+// Options configure a sandbox
+type Options struct {
+	Addr    string   // Listen address, e.g. 127.0.0.1:8765 (default is a random port)
+	Devices int      // Number of simulated devices
+	Offline int      // How many of the devices are offline
+	Pending int      // Additional devices waiting for approval
+	Groups  []string // Groups the devices are spread over
+	Shell   string   // Shell started for terminal sessions (default /bin/sh, cmd.exe on Windows)
+	DataDir string   // Directory holding the device file systems (default is a temporary directory)
+	APIKey  string   // API key accepted besides user logins (default is random)
+
+	// AutoLogin completes browser logins without visiting the login page
+	AutoLogin bool
+}
+
+// Server is a running sandbox
+type Server struct {
+	opts     Options
+	listener net.Listener
+	server   *http.Server
+	url      string
+	dataDir  string
+	tempDir  bool
+
+	key   *rsa.PrivateKey
+	keyID string
+
+	mu            sync.Mutex
+	devices       []*device
+	groups        []*api.Group
+	tokens        []*api.RegistrationToken
+	accessTokens  map[string]bool
+	refreshTokens map[string]string // refresh token -> CLI session ID
+	loginSessions map[string]*loginSession
+	cliSessions   map[string]*cliSession
+	terminals     map[string]*terminalSession
+	subscribers   map[chan models.Device]struct{}
+	bytesTransfer int64
+	stop          chan struct{} // Closed when the sandbox shuts down
+}
+
+// Start creates the simulated fleet and starts serving on opts.Addr
+func Start(opts Options) (*Server, error) {
+	if opts.Addr == "" {
+		opts.Addr = "127.0.0.1:0"
+	}
+	if opts.Devices < 0 || opts.Offline < 0 || opts.Offline > opts.Devices || opts.Pending < 0 {
+		return nil, fmt.Errorf("invalid fleet: %d devices with %d offline", opts.Devices, opts.Offline)
+	}
+	if opts.APIKey == "" {
+		opts.APIKey = "sbx_" + randomID(16)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	s := &Server{
+		opts:          opts,
+		dataDir:       opts.DataDir,
+		key:           key,
+		keyID:         randomID(8),
+		accessTokens:  make(map[string]bool),
+		refreshTokens: make(map[string]string),
+		loginSessions: make(map[string]*loginSession),
+		cliSessions:   make(map[string]*cliSession),
+		terminals:     make(map[string]*terminalSession),
+		subscribers:   make(map[chan models.Device]struct{}),
+		stop:          make(chan struct{}),
+	}
+
+	if s.dataDir == "" {
+		if s.dataDir, err = os.MkdirTemp("", "iot-sandbox-"); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		s.tempDir = true
+	}
+
+	s.createGroups()
+	s.createRegistrationTokens()
+	if err := s.createFleet(); err != nil {
+		s.removeData()
+		return nil, err
+	}
+
+	s.listener, err = net.Listen("tcp", opts.Addr)
+	if err != nil {
+		s.removeData()
+		return nil, fmt.Errorf("failed to listen on %s: %w", opts.Addr, err)
+	}
+	s.url = "http://" + s.listener.Addr().String()
+
+	s.server = &http.Server{
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() { _ = s.server.Serve(s.listener) }()
+	go s.heartbeats()
+
+	return s, nil
+}
+
+// URL returns the base URL of the sandbox
+func (s *Server) URL() string {
+	return s.url
+}
+
+// APIKey returns the API key the sandbox accepts
+func (s *Server) APIKey() string {
+	return s.opts.APIKey
+}
+
+// DataDir returns the directory holding the device file systems
+func (s *Server) DataDir() string {
+	return s.dataDir
+}
+
+// Profile returns a profile pointing the CLI at the sandbox. Logins go
+// through the sandbox's own user pool.
+func (s *Server) Profile(name string) *config.Profile {
+	return &config.Profile{
+		Name:    name,
+		APIURL:  s.url,
+		AuthURL: s.url,
+		Cognito: config.CognitoSettings{
+			Region:     "local",
+			UserPoolID: "local_sandbox",
+			ClientID:   ClientID,
+			Endpoint:   s.url,
+			Issuer:     s.url,
+		},
+	}
+}
+
+// Close stops the sandbox, ends all terminal sessions and removes the
+// temporary data directory
+func (s *Server) Close(ctx context.Context) error {
+	close(s.stop)
+	err := s.server.Shutdown(ctx)
+
+	s.mu.Lock()
+	terminals := make([]*terminalSession, 0, len(s.terminals))
+	for _, t := range s.terminals {
+		terminals = append(terminals, t)
+	}
+	s.mu.Unlock()
+	for _, t := range terminals {
+		t.close()
+	}
+
+	s.removeData()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// removeData deletes the data directory if the sandbox created it
+func (s *Server) removeData() {
+	if s.tempDir {
+		_ = os.RemoveAll(s.dataDir)
+	}
+}
+
+// heartbeats keeps the last heartbeat of online devices current and
+// publishes it
+func (s *Server) heartbeats() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for _, d := range s.devices {
+				if d.Online {
+					d.LastHeartbeat = now.UnixMilli()
+					s.publish(d)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// routes registers the API handlers
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// User pool
+	mux.HandleFunc("POST /{$}", s.handleCognito)
+	mux.HandleFunc("GET /.well-known/jwks.json", s.handleJWKS)
+	mux.HandleFunc("GET /login", s.handleLoginPage)
+	mux.HandleFunc("POST /api/auth/cli-session", s.handleCreateLoginSession)
+	mux.HandleFunc("GET /api/auth/cli-session/{id}", s.handleLoginSessionStatus)
+
+	// Authenticated API
+	authed := func(pattern string, h http.HandlerFunc) {
+		mux.Handle(pattern, s.authenticate(h))
+	}
+	authed("GET /api/auth/cli-sessions", s.handleListCLISessions)
+	authed("DELETE /api/auth/cli-sessions/{id}", s.handleRevokeCLISession)
+	authed("GET /api/tenants", s.handleTenants)
+	authed("GET /api/devices", s.handleListDevices)
+	authed("GET /api/devices/pending", s.handlePendingDevices)
+	authed("GET /api/devices/events", s.handleDeviceEvents)
+	authed("GET /api/devices/{id}", s.handleGetDevice)
+	authed("PATCH /api/devices/{id}", s.handleUpdateDevice)
+	authed("POST /api/devices/{id}/approve", s.handleApproveDevice)
+	authed("POST /api/devices/{id}/reject", s.handleRejectDevice)
+	authed("POST /api/devices/{id}/decommission", s.handleDecommissionDevice)
+	authed("GET /api/devices/{id}/files/list", s.handleListFiles)
+	authed("GET /api/devices/{id}/files/stat", s.handleStatFile)
+	authed("GET /api/devices/{id}/files/download", s.handleDownload)
+	authed("POST /api/devices/{id}/files/upload", s.handleUpload)
+	authed("POST /api/devices/{id}/files/mkdir", s.handleMkdir)
+	authed("GET /api/groups", s.handleListGroups)
+	authed("POST /api/groups", s.handleCreateGroup)
+	authed("GET /api/groups/{id}", s.handleGetGroup)
+	authed("PATCH /api/groups/{id}", s.handleRenameGroup)
+	authed("DELETE /api/groups/{id}", s.handleDeleteGroup)
+	authed("POST /api/groups/{id}/devices", s.handleAddGroupDevices)
+	authed("DELETE /api/groups/{id}/devices/{device}", s.handleRemoveGroupDevice)
+	authed("POST /api/terminal/devices/{id}/sessions", s.handleCreateTerminal)
+	authed("DELETE /api/terminal/sessions/{id}", s.handleCloseTerminal)
+	authed("GET /ws/terminal", s.handleTerminalSocket)
+	authed("GET /api/registration-tokens", s.handleListRegistrationTokens)
+	authed("POST /api/registration-tokens", s.handleCreateRegistrationToken)
+	authed("GET /api/registration-tokens/{id}", s.handleGetRegistrationToken)
+	authed("DELETE /api/registration-tokens/{id}", s.handleRevokeRegistrationToken)
+	authed("GET /api/usage", s.handleUsage)
+	authed("GET /api/usage/history", s.handleUsageHistory)
+
+	return mux
+}
+
+// authenticate rejects requests without a valid access token or API key, and
+// requests for another tenant
+func (s *Server) authenticate(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, hasBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		s.mu.Lock()
+		valid := (hasBearer && s.accessTokens[token]) || (r.Header.Get("X-API-Key") == s.opts.APIKey)
+		s.mu.Unlock()
+
+		if !valid {
+			writeError(w, http.StatusUnauthorized, "", "invalid or expired credentials")
+			return
+		}
+		if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" && tenant != TenantID {
+			writeError(w, http.StatusForbidden, "", fmt.Sprintf("not a member of tenant %q", tenant))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// randomID returns n random bytes, hex encoded
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,419 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/api"
+	"github.com/Bader-GmbH/iot-cli/internal/auth"
+	"github.com/Bader-GmbH/iot-cli/internal/terminal"
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+	"github.com/gorilla/websocket"
+)
+
+// startSandbox starts a sandbox with 5 devices, one of them offline, and 2
+// pending devices, and returns an API client using its API key
+func startSandbox(t *testing.T) (*Server, *api.Client) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server, err := Start(Options{Devices: 5, Offline: 1, Pending: 2, Groups: []string{"line-1", "line-2"}, APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close(context.Background()) })
+
+	profile := server.Profile("sandbox")
+	profile.APIKey = server.APIKey()
+	client, err := api.NewClient(profile)
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+	return server, client
+}
+
+func TestSandbox_Devices(t *testing.T) {
+	_, client := startSandbox(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		query api.DeviceQuery
+		want  int
+	}{
+		{name: "all, paged", query: api.DeviceQuery{PageSize: 2}, want: 7},
+		{name: "online", query: api.DeviceQuery{Status: "online"}, want: 6},
+		{name: "group", query: api.DeviceQuery{Group: "line-2"}, want: 2},
+		{name: "name", query: api.DeviceQuery{Name: "PRESS"}, want: 1},
+		{name: "limit", query: api.DeviceQuery{Limit: 3}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devices, err := client.CollectDevices(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("CollectDevices() unexpected error: %v", err)
+			}
+			if len(devices) != tt.want {
+				t.Errorf("got %d devices, want %d", len(devices), tt.want)
+			}
+		})
+	}
+}
+
+func TestSandbox_Approval(t *testing.T) {
+	_, client := startSandbox(t)
+	ctx := context.Background()
+
+	pending, err := client.ListPendingDevices(ctx)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("ListPendingDevices() = %d devices, %v; want 2", len(pending), err)
+	}
+
+	approved, err := client.ApproveDevice(ctx, pending[0].ID)
+	if err != nil {
+		t.Fatalf("ApproveDevice() unexpected error: %v", err)
+	}
+	if approved.Status != models.DeviceStatusApproved || approved.ApprovedAt == nil || approved.ApprovedBy == nil {
+		t.Errorf("ApproveDevice() = %+v, want an approved device with approver", approved)
+	}
+	if _, err := client.ApproveDevice(ctx, pending[0].ID); !errors.Is(err, api.ErrConflict) {
+		t.Errorf("second ApproveDevice() error = %v, want ErrConflict", err)
+	}
+
+	rejected, err := client.RejectDevice(ctx, pending[1].ID, "unknown serial")
+	if err != nil {
+		t.Fatalf("RejectDevice() unexpected error: %v", err)
+	}
+	if rejected.RejectionReason == nil || *rejected.RejectionReason != "unknown serial" {
+		t.Errorf("RejectDevice() reason = %v, want %q", rejected.RejectionReason, "unknown serial")
+	}
+
+	decommissioned, err := client.DecommissionDevice(ctx, "dev-0001")
+	if err != nil {
+		t.Fatalf("DecommissionDevice() unexpected error: %v", err)
+	}
+	if decommissioned.Status != models.DeviceStatusDecommissioned || decommissioned.Online {
+		t.Errorf("DecommissionDevice() = %+v, want an offline decommissioned device", decommissioned)
+	}
+
+	if pending, _ := client.ListPendingDevices(ctx); len(pending) != 0 {
+		t.Errorf("ListPendingDevices() after approval = %d devices, want 0", len(pending))
+	}
+}
+
+func TestSandbox_Groups(t *testing.T) {
+	_, client := startSandbox(t)
+	ctx := context.Background()
+
+	group, err := client.CreateGroup(ctx, api.CreateGroupRequest{Name: "line-3"})
+	if err != nil {
+		t.Fatalf("CreateGroup() unexpected error: %v", err)
+	}
+	if _, err := client.CreateGroup(ctx, api.CreateGroupRequest{Name: "LINE-3"}); !errors.Is(err, api.ErrConflict) {
+		t.Errorf("CreateGroup() with a taken name error = %v, want ErrConflict", err)
+	}
+
+	// dev-0005 is offline
+	if err := client.AddDevicesToGroup(ctx, group.ID, []string{"dev-0001", "dev-0005"}); err != nil {
+		t.Fatalf("AddDevicesToGroup() unexpected error: %v", err)
+	}
+	if _, err := client.RenameGroup(ctx, group.ID, "hall-b"); err != nil {
+		t.Fatalf("RenameGroup() unexpected error: %v", err)
+	}
+
+	resolved, err := client.ResolveGroup(ctx, "Hall-B")
+	if err != nil {
+		t.Fatalf("ResolveGroup() unexpected error: %v", err)
+	}
+	if resolved.DeviceCount != 2 || resolved.OnlineCount != 1 {
+		t.Errorf("group counts = %d devices, %d online; want 2, 1", resolved.DeviceCount, resolved.OnlineCount)
+	}
+
+	device, err := client.GetDevice(ctx, "dev-0001")
+	if err != nil || device.GroupName == nil || *device.GroupName != "hall-b" {
+		t.Errorf("GetDevice() = %+v, %v; want a member of hall-b", device, err)
+	}
+
+	if err := client.RemoveDeviceFromGroup(ctx, group.ID, "dev-0002"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("RemoveDeviceFromGroup() for a non-member error = %v, want ErrNotFound", err)
+	}
+	if err := client.DeleteGroup(ctx, group.ID); err != nil {
+		t.Fatalf("DeleteGroup() unexpected error: %v", err)
+	}
+	if _, err := client.ResolveGroup(ctx, "hall-b"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("ResolveGroup() after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSandbox_Labels(t *testing.T) {
+	_, client := startSandbox(t)
+	ctx := context.Background()
+
+	changes, err := api.ParseLabelChanges([]string{"site=kiel", "hw-", "role=edge"})
+	if err != nil {
+		t.Fatalf("ParseLabelChanges() unexpected error: %v", err)
+	}
+	device, err := client.UpdateDeviceLabels(ctx, "dev-0001", changes)
+	if err != nil {
+		t.Fatalf("UpdateDeviceLabels() unexpected error: %v", err)
+	}
+	if got := device.LabelString(); got != "role=edge,site=kiel" {
+		t.Errorf("labels = %s, want role=edge,site=kiel", got)
+	}
+
+	device, err = client.GetDevice(ctx, "dev-0001")
+	if err != nil || device.LabelString() != "role=edge,site=kiel" {
+		t.Errorf("GetDevice() labels = %v, %v; want role=edge,site=kiel", device, err)
+	}
+
+	bad := api.LabelChanges{"bad key": nil}
+	var apiErr *api.Error
+	if _, err := client.UpdateDeviceLabels(ctx, "dev-0001", bad); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("UpdateDeviceLabels() with an invalid key error = %v, want status 400", err)
+	}
+}
+
+func TestSandbox_RegistrationTokens(t *testing.T) {
+	_, client := startSandbox(t)
+	ctx := context.Background()
+
+	groupID := "grp-line-1"
+	created, err := client.CreateRegistrationToken(ctx, api.CreateRegistrationTokenRequest{GroupID: &groupID, MaxUses: 10})
+	if err != nil {
+		t.Fatalf("CreateRegistrationToken() unexpected error: %v", err)
+	}
+	if created.Token == "" || created.GroupName == nil || *created.GroupName != "line-1" {
+		t.Errorf("CreateRegistrationToken() = %+v, want a secret and group line-1", created)
+	}
+
+	tokens, err := client.ListRegistrationTokens(ctx)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("ListRegistrationTokens() = %d tokens, %v; want the factory token and the new one", len(tokens), err)
+	}
+	for _, token := range tokens {
+		if token.Token != "" {
+			t.Errorf("ListRegistrationTokens() includes the secret of %s", token.ID)
+		}
+	}
+
+	if err := client.RevokeRegistrationToken(ctx, created.ID); err != nil {
+		t.Fatalf("RevokeRegistrationToken() unexpected error: %v", err)
+	}
+	revoked, err := client.GetRegistrationToken(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetRegistrationToken() unexpected error: %v", err)
+	}
+	if revoked.Token != created.Token || revoked.State(time.Now()) != api.TokenRevoked {
+		t.Errorf("GetRegistrationToken() = %+v, want the revoked token with its secret", revoked)
+	}
+
+	missing := "grp-missing"
+	if _, err := client.CreateRegistrationToken(ctx, api.CreateRegistrationTokenRequest{GroupID: &missing}); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("CreateRegistrationToken() for a missing group error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSandbox_DeviceEvents(t *testing.T) {
+	server, client := startSandbox(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan models.Device, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.WatchDevices(ctx, func(d models.Device) { events <- d })
+	}()
+
+	// Wait for the subscription before changing a device
+	for subscribed := false; !subscribed; time.Sleep(10 * time.Millisecond) {
+		server.mu.Lock()
+		subscribed = len(server.subscribers) > 0
+		server.mu.Unlock()
+	}
+
+	if _, err := client.ApproveDevice(ctx, "dev-0006"); err != nil {
+		t.Fatalf("ApproveDevice() unexpected error: %v", err)
+	}
+	select {
+	case d := <-events:
+		if d.ID != "dev-0006" || d.Status != models.DeviceStatusApproved {
+			t.Errorf("event = %s %s, want dev-0006 APPROVED", d.ID, d.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event for the approved device")
+	}
+
+	cancel()
+	if err := <-done; err != nil && !errors.Is(err, context.Canceled) {
+		t.Errorf("WatchDevices() error = %v", err)
+	}
+}
+
+func TestSandbox_Files(t *testing.T) {
+	_, client := startSandbox(t)
+	ctx := context.Background()
+
+	if err := client.MkdirOnDevice(ctx, "dev-0001", "/opt/app"); err != nil {
+		t.Fatalf("MkdirOnDevice() unexpected error: %v", err)
+	}
+	if err := client.MkdirOnDevice(ctx, "dev-0001", "/opt/app"); !errors.Is(err, api.ErrConflict) {
+		t.Errorf("second MkdirOnDevice() error = %v, want ErrConflict", err)
+	}
+
+	content := "hello sandbox\n"
+	if err := client.UploadFile(ctx, "dev-0001", "/opt/app/greeting.txt", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("UploadFile() unexpected error: %v", err)
+	}
+
+	files, err := client.ListFiles(ctx, "dev-0001", "/opt/app")
+	if err != nil {
+		t.Fatalf("ListFiles() unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "/opt/app/greeting.txt" || files[0].Size != int64(len(content)) {
+		t.Errorf("ListFiles() = %+v, want the uploaded file", files)
+	}
+
+	// Paths cannot escape the device's directory
+	body, _, err := client.DownloadFile(ctx, "dev-0001", "/../../opt/app/greeting.txt")
+	if err != nil {
+		t.Fatalf("DownloadFile() unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(body)
+	body.Close()
+	if buf.String() != content {
+		t.Errorf("downloaded %q, want %q", buf.String(), content)
+	}
+
+	if _, err := client.StatFile(ctx, "dev-0001", "/missing"); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("StatFile() error = %v, want ErrNotFound", err)
+	}
+	if _, err := client.ListFiles(ctx, "dev-0005", "/"); !errors.Is(err, api.ErrDeviceOffline) {
+		t.Errorf("ListFiles() on an offline device error = %v, want ErrDeviceOffline", err)
+	}
+
+	usage, err := client.GetUsage(ctx)
+	if err != nil {
+		t.Fatalf("GetUsage() unexpected error: %v", err)
+	}
+	if usage.BytesTransferred != int64(2*len(content)) {
+		t.Errorf("BytesTransferred = %d, want %d", usage.BytesTransferred, 2*len(content))
+	}
+}
+
+func TestSandbox_Terminal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	server, client := startSandbox(t)
+	ctx := context.Background()
+
+	session, err := client.CreateTerminalSession(ctx, "dev-0002")
+	if err != nil {
+		t.Fatalf("CreateTerminalSession() unexpected error: %v", err)
+	}
+
+	headers, _ := client.AuthHeaders(ctx)
+	conn, _, err := websocket.DefaultDialer.Dial(session.WebsocketURL, headers)
+	if err != nil {
+		t.Fatalf("Dial() unexpected error: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	// readUntil reads messages until one matches
+	readUntil := func(done func(msg *terminal.AgentMessage) bool) {
+		t.Helper()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("ReadMessage() unexpected error: %v", err)
+			}
+			if msg, err := terminal.ParseMessage(data); err == nil && done(msg) {
+				return
+			}
+		}
+	}
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, terminal.BuildInputMessage([]byte("echo $((40+2)) $PWD\r"), 1)); err != nil {
+		t.Fatal(err)
+	}
+	want := "42 " + server.DataDir() + "/lathe-01"
+	var output strings.Builder
+	readUntil(func(msg *terminal.AgentMessage) bool {
+		if msg.IsOutput() {
+			output.Write(msg.Payload)
+		}
+		return strings.Contains(output.String(), want)
+	})
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, terminal.BuildInputMessage([]byte("exit 3\r"), 2)); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(func(msg *terminal.AgentMessage) bool {
+		if !msg.IsExitCode() {
+			return false
+		}
+		if string(msg.Payload) != "3" {
+			t.Errorf("exit code = %q, want 3", msg.Payload)
+		}
+		return true
+	})
+}
+
+func TestSandbox_Login(t *testing.T) {
+	server, _ := startSandbox(t)
+	ctx := context.Background()
+	profile := server.Profile("sandbox")
+
+	sessionAuth := auth.NewSessionAuth(profile)
+	session, err := sessionAuth.CreateSession(ctx)
+	if err != nil {
+		t.Fatalf("CreateSession() unexpected error: %v", err)
+	}
+
+	if status, err := sessionAuth.GetSessionStatus(ctx, session.SessionID); err != nil || status.Status != "pending" {
+		t.Fatalf("GetSessionStatus() = %+v, %v; want pending", status, err)
+	}
+
+	resp, err := http.Get(session.LoginURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	status, err := sessionAuth.GetSessionStatus(ctx, session.SessionID)
+	if err != nil || status.Status != "completed" {
+		t.Fatalf("GetSessionStatus() = %+v, %v; want completed", status, err)
+	}
+
+	cfg := auth.CognitoConfigFromProfile(profile)
+	claims, err := auth.NewVerifier(cfg, t.TempDir()).Verify(ctx, status.IDToken, auth.TokenUseID)
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if claims.TenantID != TenantID || claims.Email != UserEmail {
+		t.Errorf("claims = %+v, want the sandbox user and tenant", claims)
+	}
+
+	cognito, err := auth.NewCognitoClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cognito.RefreshTokens(ctx, status.RefreshToken); err != nil {
+		t.Errorf("RefreshTokens() unexpected error: %v", err)
+	}
+	if err := cognito.RevokeToken(ctx, status.RefreshToken); err != nil {
+		t.Fatalf("RevokeToken() unexpected error: %v", err)
+	}
+	if _, err := cognito.RefreshTokens(ctx, status.RefreshToken); err == nil {
+		t.Error("RefreshTokens() with a revoked token succeeded")
+	}
+}
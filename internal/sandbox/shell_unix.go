@@ -0,0 +1,31 @@
+//go:build !windows
+
+package sandbox
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// defaultShell is the shell started for terminal sessions
+func defaultShell() string {
+	return "/bin/sh"
+}
+
+// shellCommand creates an interactive shell in its own process group, so
+// that an interrupt reaches the command it runs
+func shellCommand(shell string) *exec.Cmd {
+	cmd := exec.Command(shell, "-i")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// interruptShell sends SIGINT to the shell's process group
+func interruptShell(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+}
+
+// killShell kills the shell and the commands it started
+func killShell(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package sandbox
+
+import (
+	"os/exec"
+)
+
+// defaultShell is the shell started for terminal sessions
+func defaultShell() string {
+	return "cmd.exe"
+}
+
+// shellCommand creates an interactive shell
+func shellCommand(shell string) *exec.Cmd {
+	return exec.Command(shell)
+}
+
+// interruptShell is not supported on Windows; the line is discarded instead
+func interruptShell(cmd *exec.Cmd) {}
+
+// killShell kills the shell
+func killShell(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
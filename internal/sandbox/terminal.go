@@ -0,0 +1,284 @@
+package sandbox
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Bader-GmbH/iot-cli/internal/terminal"
+	"github.com/gorilla/websocket"
+)
+
+// upgrader accepts terminal WebSocket connections. The sandbox only listens
+// locally, so any origin is allowed.
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// terminalSession is a terminal session on a simulated device. The device
+// side is a local shell running in the device's directory. The shell is
+// connected through pipes, so the session provides the echo and line editing
+// a terminal would.
+type terminalSession struct {
+	id     string
+	device *device
+	shell  string
+
+	writeMu sync.Mutex // Serializes WebSocket writes
+	conn    *websocket.Conn
+	seq     int64
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	exited chan struct{}
+	line   []byte
+	esc    int // Position in an escape sequence being skipped
+
+	closeOnce sync.Once
+}
+
+// handleCreateTerminal creates a terminal session that the client then
+// connects to over the WebSocket
+func (s *Server) handleCreateTerminal(w http.ResponseWriter, r *http.Request) {
+	d, ok := s.onlineDevice(w, r)
+	if !ok {
+		return
+	}
+
+	shell := s.opts.Shell
+	if shell == "" {
+		shell = defaultShell()
+	}
+	t := &terminalSession{id: randomID(16), device: d, shell: shell}
+
+	s.mu.Lock()
+	s.terminals[t.id] = t
+	s.mu.Unlock()
+
+	wsURL := "ws" + s.url[len("http"):] + "/ws/terminal?sessionId=" + t.id
+	writeJSON(w, http.StatusOK, map[string]string{
+		"sessionId":    t.id,
+		"deviceId":     d.ID,
+		"websocketUrl": wsURL,
+		"status":       "pending",
+	})
+}
+
+// handleCloseTerminal ends a terminal session
+func (s *Server) handleCloseTerminal(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	t, ok := s.terminals[r.PathValue("id")]
+	delete(s.terminals, r.PathValue("id"))
+	s.mu.Unlock()
+
+	if ok {
+		t.close()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTerminalSocket connects a client to a terminal session and starts the
+// shell
+func (s *Server) handleTerminalSocket(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("sessionId")
+
+	s.mu.Lock()
+	t, ok := s.terminals[id]
+	attached := ok && t.conn != nil
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "", "terminal session not found")
+		return
+	}
+	if attached {
+		writeError(w, http.StatusConflict, "", "terminal session is already connected")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	t.conn = conn
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.terminals, id)
+		s.mu.Unlock()
+		t.close()
+	}()
+
+	if err := t.start(); err != nil {
+		t.send(terminal.PayloadTypeOutput, []byte(fmt.Sprintf("failed to start shell: %v\r\n", err)))
+		t.send(terminal.PayloadTypeExitCode, []byte("1"))
+		return
+	}
+	t.readInput()
+}
+
+// start runs the shell and streams its output to the client
+func (t *terminalSession) start() error {
+	cmd := shellCommand(t.shell)
+	cmd.Dir = t.device.root
+	cmd.Env = append(os.Environ(),
+		"HOME="+t.device.root,
+		"PS1="+t.device.Name+"$ ",
+		"TERM=dumb",
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	output, outputWriter := io.Pipe()
+	cmd.Stdout = outputWriter
+	cmd.Stderr = outputWriter
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	t.cmd = cmd
+	t.stdin = stdin
+	t.exited = make(chan struct{})
+
+	t.send(terminal.PayloadTypeOutput, []byte(fmt.Sprintf("Connected to %s (sandbox)\r\n", t.device.Name)))
+
+	copied := make(chan struct{})
+	go func() {
+		defer close(copied)
+		buf := make([]byte, 4096)
+		for {
+			n, err := output.Read(buf)
+			if n > 0 {
+				// The client's terminal is in raw mode
+				t.send(terminal.PayloadTypeOutput, bytes.ReplaceAll(buf[:n], []byte("\n"), []byte("\r\n")))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		err := cmd.Wait()
+		close(t.exited)
+		outputWriter.Close()
+		<-copied
+
+		code := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code = exitErr.ExitCode()
+		}
+		t.send(terminal.PayloadTypeExitCode, []byte(strconv.Itoa(code)))
+		t.close()
+	}()
+
+	return nil
+}
+
+// readInput feeds the client's keystrokes to the shell until the connection
+// ends
+func (t *terminalSession) readInput() {
+	for {
+		_, data, err := t.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		msg, err := terminal.ParseMessage(data)
+		if err != nil || !msg.IsOutput() {
+			// Resize messages have no effect on a shell without a terminal
+			continue
+		}
+		t.input(msg.Payload)
+	}
+}
+
+// input applies line editing to keystrokes: printable characters are echoed
+// and collected until Enter sends the line to the shell
+func (t *terminalSession) input(data []byte) {
+	for _, b := range data {
+		// Skip escape sequences such as arrow keys
+		if t.esc > 0 {
+			if t.esc == 1 && b != '[' && b != 'O' {
+				t.esc = 0
+			} else if t.esc > 1 && b >= 0x40 && b <= 0x7e {
+				t.esc = 0
+			} else {
+				t.esc++
+			}
+			continue
+		}
+
+		switch b {
+		case 0x1b:
+			t.esc = 1
+		case '\r', '\n':
+			t.send(terminal.PayloadTypeOutput, []byte("\r\n"))
+			_, _ = t.stdin.Write(append(t.line, '\n'))
+			t.line = t.line[:0]
+		case 0x7f, 0x08:
+			if len(t.line) > 0 {
+				_, size := utf8.DecodeLastRune(t.line)
+				t.line = t.line[:len(t.line)-size]
+				t.send(terminal.PayloadTypeOutput, []byte("\b \b"))
+			}
+		case 0x03:
+			t.send(terminal.PayloadTypeOutput, []byte("^C\r\n"))
+			t.line = t.line[:0]
+			interruptShell(t.cmd)
+		case 0x04:
+			if len(t.line) == 0 {
+				_ = t.stdin.Close()
+			}
+		default:
+			if b >= 0x20 || b == '\t' {
+				t.line = append(t.line, b)
+				t.send(terminal.PayloadTypeOutput, []byte{b})
+			}
+		}
+	}
+}
+
+// send writes a b-agent protocol message to the client
+func (t *terminalSession) send(payloadType int, payload []byte) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if t.conn == nil {
+		return
+	}
+	t.seq++
+	msg := terminal.BuildMessage(terminal.MessageTypeOutputStream, payloadType, payload, t.seq)
+	_ = t.conn.WriteMessage(websocket.BinaryMessage, msg)
+}
+
+// close stops the shell and closes the connection
+func (t *terminalSession) close() {
+	t.closeOnce.Do(func() {
+		if t.cmd != nil {
+			select {
+			case <-t.exited:
+			default:
+				killShell(t.cmd)
+			}
+		}
+
+		t.writeMu.Lock()
+		defer t.writeMu.Unlock()
+		if t.conn != nil {
+			_ = t.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			t.conn.Close()
+		}
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"syscall"
 
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
 	"github.com/gorilla/websocket"
 	"golang.org/x/term"
 )
@@ -24,21 +25,20 @@ type Session struct {
 	seqNum    int64
 }
 
-// Connect establishes a WebSocket connection to the terminal session
-func Connect(ctx context.Context, baseURL, sessionID, accessToken, tenantID string) (*Session, error) {
+// Connect establishes a WebSocket connection to the terminal session,
+// authenticating with the given headers
+func Connect(ctx context.Context, baseURL, sessionID string, headers http.Header) (*Session, error) {
 	// Convert HTTP URL to WebSocket URL
 	wsURL, err := buildWebSocketURL(baseURL, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build WebSocket URL: %w", err)
 	}
 
-	// Set up headers for authentication
-	headers := http.Header{}
-	headers.Set("Authorization", "Bearer "+accessToken)
-	headers.Set("X-Tenant-ID", tenantID)
-
 	// Connect to WebSocket
-	dialer := websocket.Dialer{}
+	dialer, err := transport.WebSocketDialer()
+	if err != nil {
+		return nil, err
+	}
 	conn, resp, err := dialer.DialContext(ctx, wsURL, headers)
 	if err != nil {
 		if resp != nil {
@@ -0,0 +1,203 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Options configure the TLS and proxy settings of outbound connections
+type Options struct {
+	CABundle   string              // PEM file with root certificates trusted in addition to the system's
+	ClientCert string              // PEM client certificate for mutual TLS
+	ClientKey  string              // PEM private key of ClientCert
+	ProxyURL   string              // Proxy for all requests; empty uses HTTPS_PROXY and friends
+	NoProxy    string              // Comma-separated hosts, domains and CIDRs that bypass ProxyURL
+	Pins       map[string][]string // Accepted SPKI SHA-256 hashes (base64) per host
+}
+
+// New builds an HTTP transport from the options
+func New(opts Options) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	t.TLSClientConfig = tlsConfig
+
+	proxy, err := opts.proxy()
+	if err != nil {
+		return nil, err
+	}
+	t.Proxy = proxy
+
+	return t, nil
+}
+
+// tlsConfig returns the TLS configuration, or nil if the defaults apply
+func (o Options) tlsConfig() (*tls.Config, error) {
+	if o.CABundle == "" && o.ClientCert == "" && o.ClientKey == "" && len(o.Pins) == 0 {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if o.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(o.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no certificates", o.CABundle)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.ClientCert != "" || o.ClientKey != "" {
+		if o.ClientCert == "" || o.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(o.Pins) > 0 {
+		pins := make(map[string][]string, len(o.Pins))
+		for host, hashes := range o.Pins {
+			pins[strings.ToLower(host)] = hashes
+		}
+		cfg.VerifyConnection = verifyPins(pins)
+	}
+
+	return cfg, nil
+}
+
+// verifyPins checks that a verified chain of a pinned host contains a
+// certificate with one of the host's SPKI hashes. Hosts without pins are
+// accepted. This runs after the normal certificate verification.
+func verifyPins(pins map[string][]string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		hashes, ok := pins[strings.ToLower(cs.ServerName)]
+		if !ok {
+			return nil
+		}
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				hash := SPKIHash(cert)
+				for _, pin := range hashes {
+					if strings.TrimPrefix(pin, "sha256/") == hash {
+						return nil
+					}
+				}
+			}
+		}
+		return fmt.Errorf("%s: %w", cs.ServerName, ErrPinMismatch)
+	}
+}
+
+// SPKIHash returns the base64 SHA-256 hash of a certificate's public key,
+// as used for pinning
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// proxy returns the proxy function. Without an explicit proxy URL the
+// standard proxy environment variables are used.
+func (o Options) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if o.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(o.ProxyURL)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy_url %q", o.ProxyURL)
+	}
+
+	noProxy := parseNoProxy(o.NoProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy.matches(req.URL.Hostname()) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// noProxyList holds the parsed entries of no_proxy
+type noProxyList struct {
+	all     bool
+	hosts   []string // Exact host names; also match their subdomains
+	domains []string // Entries starting with a dot; only match subdomains
+	nets    []*net.IPNet
+}
+
+// parseNoProxy parses a comma-separated list in the NO_PROXY format
+func parseNoProxy(s string) noProxyList {
+	var list noProxyList
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			list.all = true
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			list.nets = append(list.nets, ipNet)
+			continue
+		}
+		// Ports are not distinguished
+		if host, _, err := net.SplitHostPort(entry); err == nil {
+			entry = host
+		}
+		if strings.HasPrefix(entry, ".") {
+			list.domains = append(list.domains, entry)
+		} else {
+			list.hosts = append(list.hosts, entry)
+		}
+	}
+	return list
+}
+
+// matches reports whether requests to host bypass the proxy
+func (l noProxyList) matches(host string) bool {
+	if l.all {
+		return true
+	}
+	host = strings.ToLower(host)
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range l.nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	for _, h := range l.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	for _, d := range l.domains {
+		if strings.HasSuffix(host, d) {
+			return true
+		}
+	}
+	return false
+}
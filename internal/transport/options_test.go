@@ -0,0 +1,128 @@
+package transport
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNew_CABundleAndPins(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cert := server.Certificate()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// httptest certificates are issued for example.com
+	const host = "example.com"
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{
+			name:    "untrusted without CA bundle",
+			opts:    Options{},
+			wantErr: true,
+		},
+		{
+			name: "trusted with CA bundle",
+			opts: Options{CABundle: bundle},
+		},
+		{
+			name: "matching pin",
+			opts: Options{CABundle: bundle, Pins: map[string][]string{host: {"sha256/" + SPKIHash(cert)}}},
+		},
+		{
+			name:    "mismatching pin",
+			opts:    Options{CABundle: bundle, Pins: map[string][]string{host: {"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}},
+			wantErr: true,
+		},
+		{
+			name: "pins of other hosts",
+			opts: Options{CABundle: bundle, Pins: map[string][]string{"other.example.org": {"AAAA"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, err := New(tt.opts)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if rt.TLSClientConfig == nil {
+				rt.TLSClientConfig = &tls.Config{}
+			}
+			rt.TLSClientConfig.ServerName = host
+
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := rt.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if tt.wantErr && err == nil {
+				t.Error("RoundTrip() expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("RoundTrip() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "missing CA bundle", opts: Options{CABundle: "/nonexistent/ca.pem"}},
+		{name: "client cert without key", opts: Options{ClientCert: "client.pem"}},
+		{name: "proxy without host", opts: Options{ProxyURL: "not a url"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); err == nil {
+				t.Error("New() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestNoProxy(t *testing.T) {
+	list := parseNoProxy("localhost, .internal.example.com, factory.local:8443, 10.0.0.0/8")
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost", true},
+		{"api.internal.example.com", true},
+		{"internal.example.com", false},
+		{"factory.local", true},
+		{"gw.factory.local", true},
+		{"10.1.2.3", true},
+		{"192.168.1.1", false},
+		{"api.iot.bader.solutions", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := list.matches(tt.host); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+
+	if !parseNoProxy("*").matches("anything.example.com") {
+		t.Error(`"*" should match every host`)
+	}
+}
@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces secrets in logs and HAR files
+const redacted = "REDACTED"
+
+// sensitiveHeaders are never written out
+var sensitiveHeaders = map[string]bool{
+	"Authorization":        true,
+	"X-Api-Key":            true,
+	"Cookie":               true,
+	"Set-Cookie":           true,
+	"X-Amz-Security-Token": true,
+	"Proxy-Authorization":  true,
+}
+
+// sensitiveKeyPattern matches JSON keys and form fields that carry secrets,
+// such as accessToken, refresh_token, PASSWORD or code_verifier
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)(token|password|secret|verifier|^key$|session$)`)
+
+// jsonStringField matches a "key": "value" pair in a JSON document
+var jsonStringField = regexp.MustCompile(`"([^"\\]+)"(\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+
+// isSensitiveKey reports whether a field with this name holds a secret
+func isSensitiveKey(key string) bool {
+	return sensitiveKeyPattern.MatchString(key)
+}
+
+// isSensitiveParam is isSensitiveKey for query and form parameters, which
+// also carry OAuth2 authorization codes
+func isSensitiveParam(key string) bool {
+	return key == "code" || isSensitiveKey(key)
+}
+
+// redactHeaders returns a copy of h with secret values replaced
+func redactHeaders(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactURL returns the URL with secret query parameters replaced
+func redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	clone := *u
+	query := clone.Query()
+	for k := range query {
+		if isSensitiveParam(k) {
+			query.Set(k, redacted)
+		}
+	}
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
+
+// redactBody replaces secret fields in JSON and form-encoded bodies
+func redactBody(contentType string, body []byte) string {
+	switch {
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return redacted
+		}
+		for k := range values {
+			if isSensitiveParam(k) {
+				values.Set(k, redacted)
+			}
+		}
+		return values.Encode()
+	case strings.Contains(contentType, "json"):
+		return jsonStringField.ReplaceAllStringFunc(string(body), func(field string) string {
+			m := jsonStringField.FindStringSubmatch(field)
+			if !isSensitiveKey(m[1]) {
+				return field
+			}
+			return `"` + m[1] + `"` + m[2] + `"` + redacted + `"`
+		})
+	default:
+		return string(body)
+	}
+}
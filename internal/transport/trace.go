@@ -0,0 +1,321 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCapturedBody bounds how much of a body is kept for the HAR file
+const maxCapturedBody = 1 << 20
+
+// Tracer is an http.RoundTripper that logs every request and response with
+// its timings and sizes, and records them for a HAR file. Credentials in
+// headers, query parameters and bodies are redacted.
+type Tracer struct {
+	next http.RoundTripper
+	log  io.Writer    // Receives the trace output; nil disables logging
+	har  *HARRecorder // Receives an entry per request; nil disables recording
+	mu   sync.Mutex   // Keeps the lines of one request together
+}
+
+// NewTracer wraps next with tracing to log and/or har
+func NewTracer(next http.RoundTripper, log io.Writer, har *HARRecorder) *Tracer {
+	return &Tracer{next: next, log: log, har: har}
+}
+
+// Unwrap returns the wrapped round tripper
+func (t *Tracer) Unwrap() http.RoundTripper {
+	return t.next
+}
+
+// timings collects the httptrace events of one request
+type timings struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	end          time.Time
+	remoteAddr   string
+}
+
+// set records an event time under the lock; the hooks run on several goroutines
+func (t *timings) set(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+}
+
+// clientTrace returns the httptrace hooks filling in t
+func (t *timings) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart:      func(string, string) { t.set(&t.connectStart) },
+		ConnectDone:       func(string, string, error) { t.set(&t.connectDone) },
+		TLSHandshakeStart: func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.set(&t.gotConn)
+			t.mu.Lock()
+			t.remoteAddr = info.Conn.RemoteAddr().String()
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	}
+}
+
+// phase returns the duration between two events, or -1 if one did not happen
+func phase(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() {
+		return -1
+	}
+	return to.Sub(from)
+}
+
+// summary formats the timings for the log
+func (t *timings) summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var parts []string
+	add := func(name string, d time.Duration) {
+		if d >= 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", name, d.Round(time.Microsecond*100)))
+		}
+	}
+	add("dns", phase(t.dnsStart, t.dnsDone))
+	add("connect", phase(t.connectStart, t.connectDone))
+	add("tls", phase(t.tlsStart, t.tlsDone))
+	add("ttfb", phase(t.start, t.firstByte))
+	add("total", phase(t.start, t.end))
+	return strings.Join(parts, ", ")
+}
+
+// har converts the timings to HAR timings
+func (t *timings) har() HARTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	dns := phase(t.dnsStart, t.dnsDone)
+	connect := phase(t.connectStart, t.connectDone)
+	ssl := phase(t.tlsStart, t.tlsDone)
+	if connect >= 0 && ssl > 0 {
+		// HAR counts the TLS handshake as part of connect
+		connect += ssl
+	}
+
+	blocked := phase(t.start, t.gotConn)
+	if blocked >= 0 {
+		blocked -= max(dns, 0) + max(connect, 0)
+		blocked = max(blocked, 0)
+	}
+
+	return HARTimings{
+		Blocked: milliseconds(blocked),
+		DNS:     milliseconds(dns),
+		Connect: milliseconds(connect),
+		SSL:     milliseconds(ssl),
+		Send:    max(milliseconds(phase(t.gotConn, t.wroteRequest)), 0),
+		Wait:    max(milliseconds(phase(t.wroteRequest, t.firstByte)), 0),
+		Receive: max(milliseconds(phase(t.firstByte, t.end)), 0),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	tm := &timings{start: time.Now()}
+	reqBody := captureRequestBody(req)
+
+	resp, err := t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), tm.clientTrace())))
+	if err != nil {
+		tm.set(&tm.end)
+		t.logExchange(req, nil, tm, 0, err)
+		t.record(req, reqBody, nil, nil, 0, tm, err)
+		return nil, err
+	}
+
+	body := &tracedBody{
+		ReadCloser: resp.Body,
+		capture:    isText(resp.Header.Get("Content-Type")),
+	}
+	body.finish = func() {
+		tm.set(&tm.end)
+		t.logExchange(req, resp, tm, body.size, nil)
+		t.record(req, reqBody, resp, body.buf.Bytes(), body.size, tm, nil)
+	}
+	resp.Body = body
+
+	return resp, nil
+}
+
+// logExchange writes a request and its response or error to the log
+func (t *Tracer) logExchange(req *http.Request, resp *http.Response, tm *timings, received int64, err error) {
+	if t.log == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprintf(t.log, "> %s %s\n", req.Method, redactURL(req.URL))
+	writeHeaders(t.log, ">", req.Header)
+
+	if err != nil {
+		fmt.Fprintf(t.log, "< error: %v\n", err)
+	} else {
+		fmt.Fprintf(t.log, "< %s\n", resp.Status)
+		writeHeaders(t.log, "<", resp.Header)
+	}
+
+	fmt.Fprintf(t.log, "* %s; sent %d bytes, received %d bytes\n\n", tm.summary(), max(req.ContentLength, 0), received)
+}
+
+// writeHeaders writes redacted headers in a stable order
+func writeHeaders(w io.Writer, prefix string, h http.Header) {
+	h = redactHeaders(h)
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s   %s: %s\n", prefix, name, strings.Join(h[name], ", "))
+	}
+}
+
+// record adds the exchange to the HAR recorder
+func (t *Tracer) record(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, received int64, tm *timings, err error) {
+	if t.har == nil {
+		return
+	}
+
+	entry := HAREntry{
+		StartedDateTime: tm.start,
+		Time:            milliseconds(phase(tm.start, tm.end)),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         redactURL(req.URL),
+			HTTPVersion: req.Proto,
+			Headers:     harHeaders(redactHeaders(req.Header)),
+			QueryString: harQuery(req.URL),
+			Cookies:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    max(req.ContentLength, 0),
+		},
+		Response: HARResponse{
+			Headers:     []HARNameValue{},
+			Cookies:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings:         tm.har(),
+		ServerIPAddress: tm.remoteAddr,
+	}
+
+	if reqBody != nil {
+		contentType := req.Header.Get("Content-Type")
+		entry.Request.PostData = &HARPostData{MimeType: contentType, Text: redactBody(contentType, reqBody)}
+	}
+
+	if err != nil {
+		entry.Comment = err.Error()
+	} else {
+		contentType := resp.Header.Get("Content-Type")
+		entry.Response.Status = resp.StatusCode
+		entry.Response.StatusText = http.StatusText(resp.StatusCode)
+		entry.Response.HTTPVersion = resp.Proto
+		entry.Response.Headers = harHeaders(redactHeaders(resp.Header))
+		entry.Response.BodySize = received
+		entry.Response.Content = HARContent{
+			Size:     received,
+			MimeType: contentType,
+			Text:     redactBody(contentType, respBody),
+		}
+	}
+
+	t.har.Add(entry)
+}
+
+// harQuery returns the redacted query parameters of u
+func harQuery(u *url.URL) []HARNameValue {
+	pairs := []HARNameValue{}
+	for name, values := range u.Query() {
+		for _, v := range values {
+			if isSensitiveParam(name) {
+				v = redacted
+			}
+			pairs = append(pairs, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return pairs
+}
+
+// captureRequestBody returns a copy of a textual request body without
+// consuming it, or nil if the body is binary or cannot be replayed
+func captureRequestBody(req *http.Request) []byte {
+	if req.GetBody == nil || !isText(req.Header.Get("Content-Type")) {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(io.LimitReader(body, maxCapturedBody))
+	return data
+}
+
+// isText reports whether a content type is worth capturing
+func isText(contentType string) bool {
+	return strings.Contains(contentType, "json") ||
+		strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "application/x-www-form-urlencoded")
+}
+
+// tracedBody counts and optionally captures a response body, calling
+// finish once when it has been read or closed
+type tracedBody struct {
+	io.ReadCloser
+	capture bool
+	buf     bytes.Buffer
+	size    int64
+	once    sync.Once
+	finish  func()
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if b.capture && b.buf.Len() < maxCapturedBody {
+		b.buf.Write(p[:min(n, maxCapturedBody-b.buf.Len())])
+	}
+	if err == io.EOF {
+		b.once.Do(b.finish)
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.finish)
+	return err
+}
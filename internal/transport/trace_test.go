@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTracer_RedactsAndRecords(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"accessToken":"secret-access","name":"press-01"}`))
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	har := NewHARRecorder("test")
+	client := &http.Client{Transport: NewTracer(http.DefaultTransport, &log, har)}
+
+	req, err := http.NewRequest("POST", server.URL+"/api/things?code=secret-code&page=2", strings.NewReader(`{"password":"secret-password","email":"a@example.com"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-bearer")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// The caller still sees the real response
+	if !strings.Contains(string(body), "secret-access") {
+		t.Errorf("response body = %q, want it unmodified", body)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.har")
+	if err := har.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	harData, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, out := range map[string]string{"log": log.String(), "HAR": string(harData)} {
+		for _, secret := range []string{"secret-bearer", "secret-password", "secret-access", "secret-code"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s contains %q", name, secret)
+			}
+		}
+	}
+	if !strings.Contains(log.String(), "< 200 OK") || !strings.Contains(log.String(), "ttfb") {
+		t.Errorf("log = %q, want status and timings", log.String())
+	}
+
+	var doc HAR
+	if err := json.Unmarshal(harData, &doc); err != nil {
+		t.Fatalf("HAR is not valid JSON: %v", err)
+	}
+	if len(doc.Log.Entries) != 1 {
+		t.Fatalf("HAR has %d entries, want 1", len(doc.Log.Entries))
+	}
+	entry := doc.Log.Entries[0]
+	if entry.Response.Status != 200 || entry.Response.Content.Size != int64(len(body)) {
+		t.Errorf("HAR response = %+v", entry.Response)
+	}
+	if entry.Request.PostData == nil || !strings.Contains(entry.Request.PostData.Text, "a@example.com") {
+		t.Errorf("HAR request body = %+v, want the non-secret fields kept", entry.Request.PostData)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "JSON tokens",
+			contentType: "application/json",
+			body:        `{"idToken": "a", "refresh_token":"b", "code":"DEVICE_OFFLINE"}`,
+			want:        `{"idToken": "REDACTED", "refresh_token":"REDACTED", "code":"DEVICE_OFFLINE"}`,
+		},
+		{
+			name:        "Cognito parameters",
+			contentType: "application/x-amz-json-1.1",
+			body:        `{"AuthParameters":{"USERNAME":"u","PASSWORD":"p"},"Session":"s"}`,
+			want:        `{"AuthParameters":{"USERNAME":"u","PASSWORD":"REDACTED"},"Session":"REDACTED"}`,
+		},
+		{
+			name:        "form with authorization code",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "code=abc&code_verifier=def&grant_type=authorization_code",
+			want:        "code=REDACTED&code_verifier=REDACTED&grant_type=authorization_code",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("redactBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
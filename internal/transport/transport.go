@@ -0,0 +1,95 @@
+// Package transport provides the HTTP transport shared by every outbound
+// connection of the CLI, so that TLS, proxy and tracing settings apply to
+// all of them.
+package transport
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	mu               sync.RWMutex
+	defaultTransport http.RoundTripper = http.DefaultTransport
+)
+
+// Default returns the round tripper used by clients created with NewClient
+func Default() http.RoundTripper {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultTransport
+}
+
+// SetDefault replaces the round tripper used by clients created with
+// NewClient. It is meant to be called once at startup.
+func SetDefault(rt http.RoundTripper) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultTransport = rt
+}
+
+// NewClient returns an HTTP client using the default transport. A timeout
+// of 0 means no timeout.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: Default(),
+	}
+}
+
+// ErrPinMismatch is returned when a pinned host presents none of its pinned
+// public keys
+var ErrPinMismatch = errors.New("certificate does not match any pinned public key")
+
+// ConfigError is the error of every request through a Failing round tripper
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string { return e.Err.Error() }
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// Failing returns a round tripper that fails every request with a
+// ConfigError wrapping err. It stands in for a transport that could not be
+// configured, so that commands without network access keep working.
+func Failing(err error) http.RoundTripper {
+	return failingTransport{err: err}
+}
+
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, &ConfigError{Err: t.err}
+}
+
+// WebSocketDialer returns a WebSocket dialer with the TLS and proxy settings
+// of the default transport
+func WebSocketDialer() (*websocket.Dialer, error) {
+	rt := Default()
+	for {
+		switch t := rt.(type) {
+		case *http.Transport:
+			return &websocket.Dialer{
+				Proxy:            t.Proxy,
+				TLSClientConfig:  t.TLSClientConfig.Clone(),
+				HandshakeTimeout: 45 * time.Second,
+			}, nil
+		case failingTransport:
+			return nil, t.err
+		case interface{ Unwrap() http.RoundTripper }:
+			rt = t.Unwrap()
+		default:
+			return &websocket.Dialer{
+				Proxy:            http.ProxyFromEnvironment,
+				HandshakeTimeout: 45 * time.Second,
+			}, nil
+		}
+	}
+}
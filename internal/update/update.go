@@ -11,6 +11,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/Bader-GmbH/iot-cli/internal/transport"
 )
 
 const (
@@ -70,9 +72,7 @@ func NewChecker(currentVersion, cacheDir string) *Checker {
 	return &Checker{
 		currentVersion: currentVersion,
 		cacheDir:       cacheDir,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		httpClient:     transport.NewClient(10 * time.Second),
 	}
 }
 
@@ -0,0 +1,195 @@
+// Package watch follows the state of devices over time and turns changes
+// between observations into events.
+package watch
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+// Event types
+const (
+	EventOnline         = "online"          // The device connected
+	EventOffline        = "offline"         // The device disconnected
+	EventApproved       = "approved"        // The device was approved
+	EventHeartbeatStale = "heartbeat_stale" // The device is online, but has not reported in a while
+)
+
+// Event is a change in a device's state
+type Event struct {
+	Type   string        `json:"type"`
+	Time   time.Time     `json:"time"`
+	Device models.Device `json:"device"`
+}
+
+// State is a condition a watcher can wait for
+type State string
+
+// States a watcher can wait for
+const (
+	StateOnline   State = "online"
+	StateOffline  State = "offline"
+	StateApproved State = "approved"
+)
+
+// ParseState parses a state name
+func ParseState(s string) (State, bool) {
+	switch state := State(s); state {
+	case StateOnline, StateOffline, StateApproved:
+		return state, true
+	}
+	return "", false
+}
+
+// Matches reports whether a device is in the state
+func (s State) Matches(d *models.Device) bool {
+	switch s {
+	case StateOnline:
+		return d.Online
+	case StateOffline:
+		return !d.Online
+	case StateApproved:
+		return d.Status == models.DeviceStatusApproved
+	}
+	return false
+}
+
+// tracked is what the tracker knows about a device
+type tracked struct {
+	device models.Device
+	stale  bool // A heartbeat_stale event was emitted for the current heartbeat
+}
+
+// Tracker keeps the last known state of devices and reports how they change.
+// The first observation of a device is its baseline and produces no events.
+type Tracker struct {
+	staleAfter time.Duration
+	devices    map[string]*tracked
+	accept     func(d *models.Device) bool
+}
+
+// NewTracker creates a tracker. Online devices whose last heartbeat is older
+// than staleAfter are reported as stale; 0 disables the check. Only devices
+// accept returns true for are tracked; nil tracks all devices.
+func NewTracker(staleAfter time.Duration, accept func(d *models.Device) bool) *Tracker {
+	if accept == nil {
+		accept = func(*models.Device) bool { return true }
+	}
+	return &Tracker{staleAfter: staleAfter, devices: make(map[string]*tracked), accept: accept}
+}
+
+// Observe records the current state of a device and returns the events its
+// change caused, including a stale heartbeat
+func (t *Tracker) Observe(d models.Device, now time.Time) []Event {
+	if !t.accept(&d) {
+		return nil
+	}
+
+	prev, known := t.devices[d.ID]
+	if !known {
+		t.devices[d.ID] = &tracked{device: d, stale: t.isStale(&d, now)}
+		return nil
+	}
+
+	var events []Event
+	if d.Online != prev.device.Online {
+		typ := EventOffline
+		if d.Online {
+			typ = EventOnline
+		}
+		events = append(events, Event{Type: typ, Time: now, Device: d})
+	}
+	if d.Status == models.DeviceStatusApproved && prev.device.Status != models.DeviceStatusApproved {
+		events = append(events, Event{Type: EventApproved, Time: now, Device: d})
+	}
+
+	if d.LastHeartbeat != prev.device.LastHeartbeat || d.Online != prev.device.Online {
+		prev.stale = false
+	}
+	prev.device = d
+	return append(events, t.checkStale(prev, now)...)
+}
+
+// ObserveAll records a full listing of the devices
+func (t *Tracker) ObserveAll(devices []models.Device, now time.Time) []Event {
+	var events []Event
+	for _, d := range devices {
+		events = append(events, t.Observe(d, now)...)
+	}
+	return events
+}
+
+// CheckStale returns the events of devices whose heartbeat went stale since
+// they were last observed
+func (t *Tracker) CheckStale(now time.Time) []Event {
+	var events []Event
+	for _, d := range t.sorted() {
+		events = append(events, t.checkStale(d, now)...)
+	}
+	return events
+}
+
+// Devices returns the tracked devices ordered by name
+func (t *Tracker) Devices() []models.Device {
+	sorted := t.sorted()
+	devices := make([]models.Device, 0, len(sorted))
+	for _, d := range sorted {
+		devices = append(devices, d.device)
+	}
+	return devices
+}
+
+// Stale reports whether a tracked device's heartbeat is stale
+func (t *Tracker) Stale(id string) bool {
+	d, ok := t.devices[id]
+	return ok && d.stale
+}
+
+// All reports whether every tracked device is in the state. It is false
+// while no devices are tracked.
+func (t *Tracker) All(state State) bool {
+	if len(t.devices) == 0 {
+		return false
+	}
+	for _, d := range t.devices {
+		if !state.Matches(&d.device) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkStale emits a heartbeat_stale event once per heartbeat that is older
+// than the threshold
+func (t *Tracker) checkStale(d *tracked, now time.Time) []Event {
+	if d.stale || !t.isStale(&d.device, now) {
+		return nil
+	}
+	d.stale = true
+	return []Event{{Type: EventHeartbeatStale, Time: now, Device: d.device}}
+}
+
+// isStale reports whether an online device's last heartbeat is too old
+func (t *Tracker) isStale(d *models.Device, now time.Time) bool {
+	if t.staleAfter <= 0 || !d.Online || d.LastHeartbeat == 0 {
+		return false
+	}
+	return now.Sub(time.UnixMilli(d.LastHeartbeat)) > t.staleAfter
+}
+
+// sorted returns the tracked devices ordered by name, then ID
+func (t *Tracker) sorted() []*tracked {
+	devices := make([]*tracked, 0, len(t.devices))
+	for _, d := range t.devices {
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].device.Name != devices[j].device.Name {
+			return devices[i].device.Name < devices[j].device.Name
+		}
+		return devices[i].device.ID < devices[j].device.ID
+	})
+	return devices
+}
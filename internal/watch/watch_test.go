@@ -0,0 +1,148 @@
+package watch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bader-GmbH/iot-cli/pkg/models"
+)
+
+func TestTracker_Observe(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	beat := now.UnixMilli()
+	device := func(online bool, status models.DeviceStatus, heartbeat int64) models.Device {
+		return models.Device{ID: "dev-1", Name: "press-01", Online: online, Status: status, LastHeartbeat: heartbeat}
+	}
+
+	tests := []struct {
+		name   string
+		before models.Device
+		after  models.Device
+		at     time.Time
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			before: device(true, models.DeviceStatusApproved, beat),
+			after:  device(true, models.DeviceStatusApproved, beat),
+			at:     now,
+		},
+		{
+			name:   "goes offline",
+			before: device(true, models.DeviceStatusApproved, beat),
+			after:  device(false, models.DeviceStatusApproved, beat),
+			at:     now,
+			want:   []string{EventOffline},
+		},
+		{
+			name:   "comes online",
+			before: device(false, models.DeviceStatusApproved, beat),
+			after:  device(true, models.DeviceStatusApproved, beat),
+			at:     now,
+			want:   []string{EventOnline},
+		},
+		{
+			name:   "approved",
+			before: device(true, models.DeviceStatusPending, beat),
+			after:  device(true, models.DeviceStatusApproved, beat),
+			at:     now,
+			want:   []string{EventApproved},
+		},
+		{
+			name:   "approved while coming online",
+			before: device(false, models.DeviceStatusPending, beat),
+			after:  device(true, models.DeviceStatusApproved, beat),
+			at:     now,
+			want:   []string{EventOnline, EventApproved},
+		},
+		{
+			name:   "heartbeat goes stale",
+			before: device(true, models.DeviceStatusApproved, beat),
+			after:  device(true, models.DeviceStatusApproved, beat),
+			at:     now.Add(2 * time.Minute),
+			want:   []string{EventHeartbeatStale},
+		},
+		{
+			name:   "offline devices are not stale",
+			before: device(false, models.DeviceStatusApproved, beat),
+			after:  device(false, models.DeviceStatusApproved, beat),
+			at:     now.Add(2 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(time.Minute, nil)
+			if events := tracker.Observe(tt.before, now); len(events) != 0 {
+				t.Fatalf("first observation produced %d events, want none", len(events))
+			}
+
+			events := tracker.Observe(tt.after, tt.at)
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events %+v, want %v", len(events), events, tt.want)
+			}
+			for i, e := range events {
+				if e.Type != tt.want[i] {
+					t.Errorf("event %d = %s, want %s", i, e.Type, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTracker_CheckStale(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker(time.Minute, nil)
+	tracker.Observe(models.Device{ID: "dev-1", Name: "press-01", Online: true, LastHeartbeat: now.UnixMilli()}, now)
+
+	if events := tracker.CheckStale(now.Add(30 * time.Second)); len(events) != 0 {
+		t.Fatalf("fresh heartbeat produced %d events", len(events))
+	}
+	if events := tracker.CheckStale(now.Add(2 * time.Minute)); len(events) != 1 || events[0].Type != EventHeartbeatStale {
+		t.Fatalf("stale heartbeat produced %+v, want one heartbeat_stale event", events)
+	}
+	if events := tracker.CheckStale(now.Add(3 * time.Minute)); len(events) != 0 {
+		t.Fatalf("stale heartbeat reported again: %+v", events)
+	}
+	if !tracker.Stale("dev-1") {
+		t.Error("Stale() = false after heartbeat_stale")
+	}
+
+	// A new heartbeat clears the stale state, and a later one can go stale again
+	later := now.Add(4 * time.Minute)
+	tracker.Observe(models.Device{ID: "dev-1", Name: "press-01", Online: true, LastHeartbeat: later.UnixMilli()}, later)
+	if tracker.Stale("dev-1") {
+		t.Error("Stale() = true after a new heartbeat")
+	}
+	if events := tracker.CheckStale(later.Add(2 * time.Minute)); len(events) != 1 {
+		t.Fatalf("second stale period produced %d events, want 1", len(events))
+	}
+}
+
+func TestTracker_All(t *testing.T) {
+	tracker := NewTracker(0, func(d *models.Device) bool { return d.ID != "dev-3" })
+	if tracker.All(StateOnline) {
+		t.Error("All() = true without devices")
+	}
+
+	now := time.Now()
+	tracker.ObserveAll([]models.Device{
+		{ID: "dev-1", Online: true, Status: models.DeviceStatusApproved},
+		{ID: "dev-2", Online: false, Status: models.DeviceStatusApproved},
+		{ID: "dev-3", Online: false},
+	}, now)
+	if len(tracker.Devices()) != 2 {
+		t.Fatalf("tracking %d devices, want 2", len(tracker.Devices()))
+	}
+	if tracker.All(StateOnline) {
+		t.Error("All(online) = true with an offline device")
+	}
+	if !tracker.All(StateApproved) {
+		t.Error("All(approved) = false")
+	}
+
+	tracker.Observe(models.Device{ID: "dev-2", Online: true, Status: models.DeviceStatusApproved}, now)
+	if !tracker.All(StateOnline) {
+		t.Error("All(online) = false after dev-2 came online")
+	}
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -17,19 +19,22 @@ const (
 
 // Device represents an IoT device
 type Device struct {
-	ID                  string       `json:"id"`
-	TenantID            string       `json:"tenantId"`
-	Name                string       `json:"name"`
-	Online              bool         `json:"online"`
-	LastHeartbeat       int64        `json:"lastHeartbeat"`
-	Status              DeviceStatus `json:"status"`
-	GroupID             *string      `json:"groupId,omitempty"`
-	GroupName           *string      `json:"groupName,omitempty"`
-	RegistrationTokenID *string      `json:"registrationTokenId,omitempty"`
-	ApprovedAt          *time.Time   `json:"approvedAt,omitempty"`
-	ApprovedBy          *string      `json:"approvedBy,omitempty"`
-	RejectedAt          *time.Time   `json:"rejectedAt,omitempty"`
-	DecommissionedAt    *time.Time   `json:"decommissionedAt,omitempty"`
+	ID                  string            `json:"id"`
+	TenantID            string            `json:"tenantId"`
+	Name                string            `json:"name"`
+	Online              bool              `json:"online"`
+	LastHeartbeat       int64             `json:"lastHeartbeat"`
+	Status              DeviceStatus      `json:"status"`
+	GroupID             *string           `json:"groupId,omitempty"`
+	GroupName           *string           `json:"groupName,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	RegistrationTokenID *string           `json:"registrationTokenId,omitempty"`
+	ApprovedAt          *time.Time        `json:"approvedAt,omitempty"`
+	ApprovedBy          *string           `json:"approvedBy,omitempty"`
+	RejectedAt          *time.Time        `json:"rejectedAt,omitempty"`
+	RejectedBy          *string           `json:"rejectedBy,omitempty"`
+	RejectionReason     *string           `json:"rejectionReason,omitempty"`
+	DecommissionedAt    *time.Time        `json:"decommissionedAt,omitempty"`
 }
 
 // LastSeenString returns a human-readable string for when the device was last seen
@@ -73,3 +78,18 @@ func (d *Device) OnlineStatus() string {
 	}
 	return "offline"
 }
+
+// LabelString returns the labels as key=value pairs ordered by key
+func (d *Device) LabelString() string {
+	keys := make([]string, 0, len(d.Labels))
+	for k := range d.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+d.Labels[k])
+	}
+	return strings.Join(pairs, ",")
+}